@@ -10,13 +10,16 @@ import (
 // PingCommand implements the PING command
 type PingCommand struct{}
 
-func (c *PingCommand) Execute(args []string, cache storage.Cache) string {
-	return "+PONG" + protocol.CRLF
+func (c *PingCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	if len(args) == 2 {
+		return protocol.BulkString(args[1])
+	}
+	return protocol.SimpleString("PONG")
 }
 
 func (c *PingCommand) Validate(args []string) error {
-	// PING command takes no arguments
-	if len(args) > 1 {
+	// PING takes an optional message
+	if len(args) > 2 {
 		return errors.New("wrong number of arguments for 'ping' command")
 	}
 	return nil
@@ -25,16 +28,11 @@ func (c *PingCommand) Validate(args []string) error {
 // EchoCommand implements the ECHO command
 type EchoCommand struct{}
 
-func (c *EchoCommand) Execute(args []string, cache storage.Cache) string {
-	if len(args) < 2 {
-		return protocol.BuildError("wrong number of arguments for 'echo' command")
-	}
-	return protocol.BuildBulkString(args[1])
+func (c *EchoCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return protocol.BulkString(args[1])
 }
 
 func (c *EchoCommand) Validate(args []string) error {
-	if len(args) < 2 {
-		return errors.New("wrong number of arguments for 'echo' command")
-	}
+	// Arity is checked by the registry
 	return nil
 }
@@ -0,0 +1,222 @@
+package commands
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+)
+
+// blockingKey is a key of a given database that clients may block on
+type blockingKey struct {
+	db  int
+	key string
+}
+
+// blockedClient is a client waiting for one of its keys to be served
+type blockedClient struct {
+	client *Client
+	keys   []blockingKey
+	// try attempts to serve the client from key of cache, it returns nil
+	// when key has nothing for it
+	try func(cache storage.Cache, key string) protocol.Reply
+	// command is the blocked command, propagated by the write serving it
+	command *QueueCommand
+	reply   chan protocol.Reply
+	// served is set once a reply was sent, a client blocked twice on the
+	// same key must not be served twice
+	served bool
+}
+
+// BlockedClients keeps track of the clients blocked on keys, like the
+// blocking_keys dict of Redis. Every key has a FIFO queue of the clients
+// waiting for it, so the client that blocked first is served first.
+//
+// Clients aren't woken by polling: the writes of a connection mark their keys
+// as ready on its Client, and once the replies and the replication of the
+// write are done the connection calls ServeReady, which serves the waiters of
+// those keys in order and propagates the pops right away. Replicas see the
+// write, then the pops it unblocked, before anything else the writer does.
+//
+// A blocked client also stops waiting when its timeout expires, when its
+// connection goes away or when CLIENT UNBLOCK names it.
+type BlockedClients struct {
+	// databases resolves the database of a key when it gets served, SWAPDB
+	// may have changed what is behind its index since the client blocked
+	databases *storage.Databases
+	mu        sync.Mutex
+	waiters   map[blockingKey][]*blockedClient
+	// clients indexes the waiters by client ID for CLIENT UNBLOCK
+	clients map[int64]*blockedClient
+}
+
+func NewBlockedClients(databases *storage.Databases) *BlockedClients {
+	return &BlockedClients{
+		databases: databases,
+		waiters:   make(map[blockingKey][]*blockedClient),
+		clients:   make(map[int64]*blockedClient),
+	}
+}
+
+// Block serves the client from the first of keys try has something for in
+// cache, the selected database of the client. When none has, the client waits
+// until a write makes try succeed or the timeout expires, zero meaning
+// forever, in which case nil is returned. A disconnection counts as a timeout
+// and CLIENT UNBLOCK picks the reply. Inside EXEC the client never waits,
+// like in Redis.
+func (b *BlockedClients) Block(client *Client, cache storage.Cache, keys []string, timeout time.Duration, try func(cache storage.Cache, key string) protocol.Reply) protocol.Reply {
+	waiter := &blockedClient{
+		client:  client,
+		keys:    make([]blockingKey, 0, len(keys)),
+		try:     try,
+		command: client.running,
+		reply:   make(chan protocol.Reply, 1),
+	}
+
+	// The keys are checked under the lock so that a write landing right
+	// after the check finds the client queued
+	b.mu.Lock()
+	for _, key := range keys {
+		if reply := try(cache, key); reply != nil {
+			b.mu.Unlock()
+			return reply
+		}
+	}
+	if client.inExec {
+		b.mu.Unlock()
+		return nil
+	}
+	for _, key := range keys {
+		bk := blockingKey{db: client.DB, key: key}
+		waiter.keys = append(waiter.keys, bk)
+		b.waiters[bk] = append(b.waiters[bk], waiter)
+	}
+	b.clients[client.ID] = waiter
+	b.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case reply := <-waiter.reply:
+		return reply
+	case <-expired:
+	case <-client.disconnected:
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// A write may have served the client while it stopped waiting
+	select {
+	case reply := <-waiter.reply:
+		return reply
+	default:
+	}
+	b.remove(waiter)
+	return nil
+}
+
+// remove takes waiter out of the queues of all its keys, it must be called
+// with the lock held
+func (b *BlockedClients) remove(waiter *blockedClient) {
+	for _, bk := range waiter.keys {
+		queue := b.waiters[bk]
+		for i, w := range queue {
+			if w == waiter {
+				queue = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+		if len(queue) == 0 {
+			delete(b.waiters, bk)
+		} else {
+			b.waiters[bk] = queue
+		}
+	}
+	delete(b.clients, waiter.client.ID)
+}
+
+// Unblock makes the command of the blocked client with the given ID return
+// reply, nil standing for a timeout. It reports whether the client was
+// blocked.
+func (b *BlockedClients) Unblock(id int64, reply protocol.Reply) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	waiter, exists := b.clients[id]
+	if !exists || waiter.served {
+		return false
+	}
+	b.remove(waiter)
+	waiter.served = true
+	waiter.reply <- reply
+	return true
+}
+
+// ServeReady serves the clients blocked on the keys client wrote since the
+// last call, in the order they blocked. The effect of each served command is
+// handed to propagate from here rather than from the connection of the
+// served client, which would race with the next commands of the writer.
+func (b *BlockedClients) ServeReady(client *Client, propagate func(db int, cmds [][]string)) {
+	if len(client.readyKeys) == 0 {
+		return
+	}
+	ready := client.readyKeys
+	client.readyKeys = nil
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, bk := range ready {
+		cache := b.databases.Get(bk.db)
+		// Serving a client removes it from the queue, so walk a copy
+		for _, waiter := range append([]*blockedClient(nil), b.waiters[bk]...) {
+			if waiter.served {
+				continue
+			}
+			if reply := waiter.try(cache, bk.key); reply != nil {
+				b.remove(waiter)
+				waiter.served = true
+				if waiter.command != nil {
+					propagate(bk.db, waiter.command.Propagation(reply))
+					waiter.command.propagated = true
+				}
+				waiter.reply <- reply
+			}
+		}
+	}
+}
+
+// scanDatabaseForReadyKeys marks the keys of database db that clients are
+// blocked on as ready on client when they exist, after SWAPDB or MOVE made
+// them appear without a write. Like the function of the same name in Redis.
+func (b *BlockedClients) scanDatabaseForReadyKeys(client *Client, db int) {
+	cache := b.databases.Get(db)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for bk := range b.waiters {
+		if bk.db == db && cache.Exists(bk.key) {
+			client.signalKeyAsReady(db, bk.key)
+		}
+	}
+}
+
+// parseBlockTimeout parses the timeout of the blocking commands, in seconds
+func parseBlockTimeout(arg string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(arg, 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return 0, errors.New("timeout is not a float or out of range")
+	}
+	if seconds < 0 {
+		return 0, errors.New("timeout is negative")
+	}
+	if seconds > float64(math.MaxInt64/time.Second) {
+		return 0, errors.New("timeout is out of range")
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
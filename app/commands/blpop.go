@@ -1,71 +1,86 @@
 package commands
 
 import (
-	"fmt"
-	"strconv"
-	"time"
-
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/storage"
+	"github.com/codecrafters-io/redis-starter-go/app/types"
 )
 
-type BLPopCommand struct{}
+type BLPopCommand struct {
+	blocked *BlockedClients
+}
+
+// lpopFrom pops the first element of the list stored at key, it returns nil
+// when the key doesn't hold a non empty list
+func (b *BLPopCommand) lpopFrom(cache storage.Cache, key string) protocol.Reply {
+	redisValue, exists := cache.Get(key)
+	if !exists {
+		return nil
+	}
+	listValue, correctType := redisValue.(*storage.ListValue)
+	if !correctType {
+		return nil
+	}
+
+	val := listValue.Lpop()
+	if val == nil {
+		return nil
+	}
+	// Like Redis, a list that has been emptied stops existing
+	if listValue.Size() == 0 {
+		cache.Delete(key)
+	}
+	return protocol.NewArray([]any{key, val.Value})
+}
 
-func (b *BLPopCommand) handleBlockCommand(cache storage.Cache, key string) string {
-	for {
-		time.Sleep(50 * time.Millisecond)
+// Execute implements Command.
+func (b *BLPopCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return errExecuteUnreachable
+}
+
+// ExecuteWithClient implements ClientAwareCommand.
+func (b *BLPopCommand) ExecuteWithClient(args []string, cache storage.Cache, metadata *types.ServerMetadata, client *Client) protocol.Reply {
+	timeout, _ := parseBlockTimeout(args[len(args)-1])
+	keys := args[1 : len(args)-1]
 
+	// Like Redis, a key of the wrong type only fails the command when no
+	// list before it has an element to pop
+	for _, key := range keys {
 		redisValue, exists := cache.Get(key)
 		if !exists {
 			continue
 		}
-
 		listValue, correctType := redisValue.(*storage.ListValue)
 		if !correctType {
-			continue
+			return protocol.ErrWrongType
 		}
-
-		if val := listValue.Lpop(); val != nil {
-			return protocol.BuildArray([]any{key, val.Value})
+		if listValue.Size() > 0 {
+			break
 		}
 	}
-}
 
-// Execute implements Command.
-func (b *BLPopCommand) Execute(args []string, cache storage.Cache) string {
-	key := args[1]
-	timeout, _ := strconv.ParseFloat(args[2], 64)
-	delta := int(timeout * 1000)
-
-	redisValue, exists := cache.Get(key)
-	if !exists {
-		if delta == 0 {
-			return b.handleBlockCommand(cache, key) // Block indefinitely
-		} else {
-			time.Sleep(time.Duration(delta) * time.Millisecond)
-		}
-	}
-
-	redisValue, exists = cache.Get(key)
-	if !exists {
-		return protocol.BuildNullArray()
-	}
-	listValue, correctType := redisValue.(*storage.ListValue)
-	if !correctType {
-		return protocol.BuildError("wrongtype of BLPOP command")
-	}
-
-	if val := listValue.Lpop(); val != nil {
-		return protocol.BuildArray([]any{key, val.Value})
-	} else {
-		return protocol.BuildNullArray()
+	reply := b.blocked.Block(client, cache, keys, timeout, func(cache storage.Cache, key string) protocol.Reply {
+		return b.lpopFrom(cache, key)
+	})
+	if reply == nil {
+		return protocol.NullArray{}
 	}
+	return reply
 }
 
 // Validate implements Command.
 func (b *BLPopCommand) Validate(args []string) error {
-	if len(args) != 3 {
-		return fmt.Errorf("invalid number of argument to BLPOP")
+	_, err := parseBlockTimeout(args[len(args)-1])
+	return err
+}
+
+// Propagate implements Propagator, replicas apply the pop that was served
+// rather than blocking themselves
+func (b *BLPopCommand) Propagate(args []string, reply protocol.Reply) [][]string {
+	popped, ok := reply.(protocol.Array)
+	if !ok || len(popped) != 2 {
+		return nil
 	}
-	return nil
+	key, _ := popped[0].(protocol.BulkString)
+	return [][]string{{"LPOP", string(key)}}
 }
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+var nextClientID atomic.Int64
+
+// Client holds the per-connection state that commands may need to read or
+// change, such as the protocol version negotiated with HELLO
+type Client struct {
+	ID       int64
+	Name     string
+	Protocol int
+	// DB is the index of the selected database
+	DB int
+	// IsMaster is set on the link a replica receives the stream of its
+	// master on, the commands coming from it see expired keys
+	IsMaster bool
+	// readyKeys are the keys written by the client that blocked clients may
+	// be waiting for, see BlockedClients.ServeReady
+	readyKeys []blockingKey
+	// inExec is set while EXEC runs the queued commands, blocking commands
+	// don't wait then
+	inExec bool
+	// running is the command the client is executing, so that the write
+	// serving it while it is blocked can propagate it
+	running *QueueCommand
+	// disconnected is closed once the connection is gone, so that a blocked
+	// command stops waiting for it
+	disconnected   chan struct{}
+	disconnectOnce sync.Once
+}
+
+// NewClient creates the state for a freshly accepted connection, every
+// connection starts out speaking RESP2
+func NewClient() *Client {
+	return &Client{
+		ID:           nextClientID.Add(1),
+		Protocol:     protocol.RESP2,
+		disconnected: make(chan struct{}),
+	}
+}
+
+// Disconnect records that the connection of the client is gone, a command
+// it is blocked in returns as if it timed out
+func (c *Client) Disconnect() {
+	c.disconnectOnce.Do(func() { close(c.disconnected) })
+}
+
+// signalKeysAsReady records that the client wrote keys in its selected
+// database
+func (c *Client) signalKeysAsReady(keys []string) {
+	for _, key := range keys {
+		c.signalKeyAsReady(c.DB, key)
+	}
+}
+
+// signalKeyAsReady records that the client made key of database db exist
+func (c *Client) signalKeyAsReady(db int, key string) {
+	c.readyKeys = append(c.readyKeys, blockingKey{db: db, key: key})
+}
+
+// IsRESP3 reports whether the client negotiated RESP3 with HELLO
+func (c *Client) IsRESP3() bool {
+	return c.Protocol == protocol.RESP3
+}
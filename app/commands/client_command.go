@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+	"github.com/codecrafters-io/redis-starter-go/app/types"
+)
+
+// ClientCommand implements CLIENT ID and CLIENT UNBLOCK
+type ClientCommand struct {
+	blocked *BlockedClients
+}
+
+// Execute implements Command.
+func (c *ClientCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return errExecuteUnreachable
+}
+
+// Validate implements Command.
+func (c *ClientCommand) Validate(args []string) error {
+	switch strings.ToUpper(args[1]) {
+	case "ID":
+		if len(args) != 2 {
+			return errors.New("wrong number of arguments for 'client|id' command")
+		}
+		return nil
+	case "UNBLOCK":
+		if len(args) != 3 && len(args) != 4 {
+			return errors.New("wrong number of arguments for 'client|unblock' command")
+		}
+		if _, err := strconv.ParseInt(args[2], 10, 64); err != nil {
+			return errors.New(protocol.NOT_AN_INTEGER)
+		}
+		if len(args) == 4 {
+			switch strings.ToUpper(args[3]) {
+			case "TIMEOUT", "ERROR":
+			default:
+				return errors.New("CLIENT UNBLOCK reason should be TIMEOUT or ERROR")
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown subcommand '%s'. Try CLIENT HELP.", args[1])
+}
+
+// ExecuteWithClient implements ClientAwareCommand.
+func (c *ClientCommand) ExecuteWithClient(args []string, cache storage.Cache, metadata *types.ServerMetadata, client *Client) protocol.Reply {
+	if strings.ToUpper(args[1]) == "ID" {
+		return protocol.Integer(client.ID)
+	}
+
+	// The blocked command replies as if it timed out unless told otherwise
+	id, _ := strconv.ParseInt(args[2], 10, 64)
+	var reply protocol.Reply
+	if len(args) == 4 && strings.ToUpper(args[3]) == "ERROR" {
+		reply = protocol.ErrUnblocked
+	}
+	if c.blocked.Unblock(id, reply) {
+		return protocol.Integer(1)
+	}
+	return protocol.Integer(0)
+}
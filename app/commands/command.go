@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+)
+
+// CommandCommand implements COMMAND and its COUNT, INFO, DOCS, GETKEYS and
+// LIST subcommands on top of the registry metadata
+type CommandCommand struct {
+	registry *CommandRegistry
+}
+
+// Execute implements Command.
+func (c *CommandCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	if len(args) == 1 {
+		return c.infoReply(c.registry.CommandInfos())
+	}
+
+	switch strings.ToUpper(args[1]) {
+	case "COUNT":
+		return protocol.Integer(len(c.registry.infos))
+	case "LIST":
+		infos := c.registry.CommandInfos()
+		names := make([]string, 0, len(infos))
+		for _, info := range infos {
+			names = append(names, info.Name)
+		}
+		return protocol.NewStringArray(names)
+	case "INFO":
+		if len(args) == 2 {
+			return c.infoReply(c.registry.CommandInfos())
+		}
+		reply := make(protocol.Array, 0, len(args)-2)
+		for _, name := range args[2:] {
+			info, exists := c.registry.GetCommandInfo(name)
+			if !exists {
+				reply = append(reply, protocol.NullArray{})
+				continue
+			}
+			reply = append(reply, commandInfoReply(info))
+		}
+		return reply
+	case "DOCS":
+		infos := c.registry.CommandInfos()
+		if len(args) > 2 {
+			infos = make([]*CommandInfo, 0, len(args)-2)
+			for _, name := range args[2:] {
+				// Unknown commands are left out of the reply
+				if info, exists := c.registry.GetCommandInfo(name); exists {
+					infos = append(infos, info)
+				}
+			}
+		}
+		reply := make(protocol.Map, 0, len(infos))
+		for _, info := range infos {
+			reply = append(reply, protocol.MapEntry{
+				Key:   protocol.BulkString(info.Name),
+				Value: commandDocsReply(info),
+			})
+		}
+		return reply
+	case "GETKEYS":
+		return c.getKeys(args[2:])
+	}
+	return protocol.NewError(fmt.Sprintf("unknown subcommand '%s'. Try COMMAND HELP.", args[1]))
+}
+
+// Validate implements Command.
+func (c *CommandCommand) Validate(args []string) error {
+	if len(args) == 1 {
+		return nil
+	}
+
+	subcommand := strings.ToUpper(args[1])
+	switch subcommand {
+	case "COUNT", "LIST":
+		if len(args) != 2 {
+			return fmt.Errorf("wrong number of arguments for 'command|%s' command", strings.ToLower(subcommand))
+		}
+	case "GETKEYS":
+		if len(args) < 3 {
+			return errors.New("wrong number of arguments for 'command|getkeys' command")
+		}
+	case "INFO", "DOCS":
+	default:
+		return fmt.Errorf("unknown subcommand '%s'. Try COMMAND HELP.", args[1])
+	}
+	return nil
+}
+
+func (c *CommandCommand) getKeys(request []string) protocol.Reply {
+	info, exists := c.registry.GetCommandInfo(request[0])
+	if !exists {
+		return protocol.NewError("Invalid command specified")
+	}
+	if err := info.CheckArity(request); err != nil {
+		return protocol.NewError("Invalid number of arguments specified for command")
+	}
+
+	keys := c.registry.GetKeys(request)
+	if len(keys) == 0 {
+		return protocol.NewError("The command has no key arguments")
+	}
+	return protocol.NewStringArray(keys)
+}
+
+func (c *CommandCommand) infoReply(infos []*CommandInfo) protocol.Reply {
+	reply := make(protocol.Array, 0, len(infos))
+	for _, info := range infos {
+		reply = append(reply, commandInfoReply(info))
+	}
+	return reply
+}
+
+// commandInfoReply builds the 10 element COMMAND INFO entry: name, arity,
+// flags, first key, last key, step, ACL categories, tips, key specs and
+// subcommands
+func commandInfoReply(info *CommandInfo) protocol.Reply {
+	return protocol.Array{
+		protocol.BulkString(info.Name),
+		protocol.Integer(info.Arity),
+		statusSet(info.FlagNames()),
+		protocol.Integer(info.FirstKey),
+		protocol.Integer(info.LastKey),
+		protocol.Integer(info.Step),
+		statusSet(info.ACLCategories()),
+		protocol.Array{},
+		keySpecsReply(info),
+		protocol.Array{},
+	}
+}
+
+// keySpecsReply describes the key positions as a single index/range key spec
+func keySpecsReply(info *CommandInfo) protocol.Reply {
+	if info.FirstKey <= 0 {
+		return protocol.Array{}
+	}
+
+	flags := []string{"RW", "ACCESS"}
+	if info.Has(FlagWrite) {
+		flags = []string{"RW", "UPDATE"}
+	} else if info.Has(FlagReadOnly) {
+		flags = []string{"RO", "ACCESS"}
+	}
+
+	return protocol.Array{protocol.Map{
+		{Key: protocol.BulkString("flags"), Value: statusSet(flags)},
+		{Key: protocol.BulkString("begin_search"), Value: protocol.Map{
+			{Key: protocol.BulkString("type"), Value: protocol.BulkString("index")},
+			{Key: protocol.BulkString("spec"), Value: protocol.Map{
+				{Key: protocol.BulkString("index"), Value: protocol.Integer(info.FirstKey)},
+			}},
+		}},
+		{Key: protocol.BulkString("find_keys"), Value: protocol.Map{
+			{Key: protocol.BulkString("type"), Value: protocol.BulkString("range")},
+			{Key: protocol.BulkString("spec"), Value: protocol.Map{
+				{Key: protocol.BulkString("lastkey"), Value: protocol.Integer(lastKeyOffset(info))},
+				{Key: protocol.BulkString("keystep"), Value: protocol.Integer(max(info.Step, 1))},
+				{Key: protocol.BulkString("limit"), Value: protocol.Integer(0)},
+			}},
+		}},
+	}}
+}
+
+// lastKeyOffset converts LastKey to the key spec convention, which is
+// relative to FirstKey unless it counts from the end
+func lastKeyOffset(info *CommandInfo) int {
+	if info.LastKey < 0 {
+		return info.LastKey
+	}
+	return info.LastKey - info.FirstKey
+}
+
+func commandDocsReply(info *CommandInfo) protocol.Reply {
+	return protocol.Map{
+		{Key: protocol.BulkString("summary"), Value: protocol.BulkString(info.Summary)},
+		{Key: protocol.BulkString("group"), Value: protocol.BulkString(info.Group)},
+	}
+}
+
+func statusSet(values []string) protocol.Set {
+	set := make(protocol.Set, 0, len(values))
+	for _, value := range values {
+		set = append(set, protocol.SimpleString(value))
+	}
+	return set
+}
@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CommandFlag describes a property of a command that the server acts on,
+// e.g. FlagWrite commands are replicated and refused by read only replicas
+type CommandFlag uint32
+
+const (
+	FlagWrite CommandFlag = 1 << iota
+	FlagReadOnly
+	FlagDenyOOM
+	FlagBlocking
+	FlagAdmin
+	FlagPubSub
+	FlagNoScript
+	FlagLoadingOK
+	FlagStaleOK
+	FlagFast
+	FlagMovableKeys
+)
+
+// flagNames are the names COMMAND reports, in reply order
+var flagNames = []struct {
+	flag CommandFlag
+	name string
+}{
+	{FlagWrite, "write"},
+	{FlagReadOnly, "readonly"},
+	{FlagDenyOOM, "denyoom"},
+	{FlagAdmin, "admin"},
+	{FlagPubSub, "pubsub"},
+	{FlagNoScript, "noscript"},
+	{FlagBlocking, "blocking"},
+	{FlagLoadingOK, "loading"},
+	{FlagStaleOK, "stale"},
+	{FlagFast, "fast"},
+	{FlagMovableKeys, "movablekeys"},
+}
+
+// Command groups, each one is also an ACL category
+const (
+	GroupGeneric      = "generic"
+	GroupString       = "string"
+	GroupList         = "list"
+	GroupStream       = "stream"
+	GroupHash         = "hash"
+	GroupSet          = "set"
+	GroupSortedSet    = "sorted-set"
+	GroupConnection   = "connection"
+	GroupServer       = "server"
+	GroupTransactions = "transactions"
+)
+
+var groupCategories = map[string]string{
+	GroupGeneric:      "@keyspace",
+	GroupString:       "@string",
+	GroupList:         "@list",
+	GroupStream:       "@stream",
+	GroupHash:         "@hash",
+	GroupSet:          "@set",
+	GroupSortedSet:    "@sortedset",
+	GroupConnection:   "@connection",
+	GroupServer:       "@admin",
+	GroupTransactions: "@transaction",
+}
+
+// CommandInfo is the metadata a command is registered with
+type CommandInfo struct {
+	Name string
+	// Arity counts the command name itself. A positive arity is exact, a
+	// negative one is the minimum number of arguments.
+	Arity int
+	Flags CommandFlag
+	// FirstKey, LastKey and Step locate the key arguments. A negative LastKey
+	// counts from the end, zero FirstKey means the command takes no keys.
+	FirstKey int
+	LastKey  int
+	Step     int
+	Group    string
+	// Summary is the one line description COMMAND DOCS reports
+	Summary string
+}
+
+// Has reports whether all the given flags are set
+func (i *CommandInfo) Has(flags CommandFlag) bool {
+	return i.Flags&flags == flags
+}
+
+// CheckArity validates the number of arguments against Arity
+func (i *CommandInfo) CheckArity(args []string) error {
+	if (i.Arity > 0 && len(args) != i.Arity) || len(args) < -i.Arity {
+		return fmt.Errorf("wrong number of arguments for '%s' command", i.Name)
+	}
+	return nil
+}
+
+// FlagNames returns the names of the flags that are set
+func (i *CommandInfo) FlagNames() []string {
+	names := make([]string, 0)
+	for _, f := range flagNames {
+		if i.Has(f.flag) {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// ACLCategories derives the ACL categories of the command from its flags
+// and group
+func (i *CommandInfo) ACLCategories() []string {
+	categories := make([]string, 0)
+	if i.Has(FlagWrite) {
+		categories = append(categories, "@write")
+	}
+	if i.Has(FlagReadOnly) {
+		categories = append(categories, "@read")
+	}
+	if i.Has(FlagAdmin) {
+		categories = append(categories, "@admin", "@dangerous")
+	}
+	if i.Has(FlagPubSub) {
+		categories = append(categories, "@pubsub")
+	}
+	if i.Has(FlagFast) {
+		categories = append(categories, "@fast")
+	} else {
+		categories = append(categories, "@slow")
+	}
+	if i.Has(FlagBlocking) {
+		categories = append(categories, "@blocking")
+	}
+	if category, ok := groupCategories[i.Group]; ok && !containsString(categories, category) {
+		categories = append(categories, category)
+	}
+	return categories
+}
+
+// KeyPositions returns the indexes of the key arguments in args
+func (i *CommandInfo) KeyPositions(args []string) []int {
+	positions := make([]int, 0)
+	if i.FirstKey <= 0 {
+		return positions
+	}
+	last := i.LastKey
+	if last < 0 {
+		last = len(args) + last
+	}
+	step := max(i.Step, 1)
+	for pos := i.FirstKey; pos <= last && pos < len(args); pos += step {
+		positions = append(positions, pos)
+	}
+	return positions
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
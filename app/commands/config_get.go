@@ -2,41 +2,62 @@ package commands
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
+	"github.com/codecrafters-io/redis-starter-go/app/glob"
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/storage"
 	"github.com/codecrafters-io/redis-starter-go/app/types"
-	"github.com/codecrafters-io/redis-starter-go/app/utility"
 )
 
 type ConfigGetCommand struct{}
 
 // Execute implements Command.
-func (c *ConfigGetCommand) Execute(args []string, cache storage.Cache) string {
-	return "This function shoudn't be called"
+func (c *ConfigGetCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return errExecuteUnreachable
 }
 
 // Validate implements Command.
 func (c *ConfigGetCommand) Validate(args []string) error {
+	if strings.ToUpper(args[1]) != "GET" {
+		return fmt.Errorf("unknown subcommand '%s'. Try CONFIG HELP.", args[1])
+	}
 	if len(args) < 3 {
-		return fmt.Errorf("wrong number of arguments to `config get` command")
+		return fmt.Errorf("wrong number of arguments for 'config|get' command")
 	}
 	return nil
 }
 
-func (c *ConfigGetCommand) ExecuteWithMetadata(args []string, cache storage.Cache, metadata *types.ServerMetadata) []string {
-	param := strings.ToLower(args[2])
-	result := make([]string, 0)
-	switch param {
-	case "dir":
-		result = append(result, param)
-		result = append(result, metadata.Dir)
-	case "dbfilename":
-		result = append(result, param)
-		result = append(result, metadata.DbFileName)
-	default:
-		result = append(result, "Invalid parameter")
+func (c *ConfigGetCommand) ExecuteWithMetadata(args []string, cache storage.Cache, metadata *types.ServerMetadata) protocol.Reply {
+	// RESP2 clients receive the map flattened into an array
+	reply := make(protocol.Map, 0)
+	for _, param := range configParams(metadata) {
+		for _, pattern := range args[2:] {
+			if glob.MatchNoCase(pattern, param.name) {
+				reply = append(reply, protocol.MapEntry{
+					Key:   protocol.BulkString(param.name),
+					Value: protocol.BulkString(param.value),
+				})
+				break
+			}
+		}
+	}
+	return reply
+}
+
+type configParam struct {
+	name  string
+	value string
+}
+
+// configParams lists the parameters CONFIG GET knows about
+func configParams(metadata *types.ServerMetadata) []configParam {
+	return []configParam{
+		{"dir", metadata.Dir},
+		{"dbfilename", metadata.DbFileName},
+		{"maxmemory", strconv.FormatInt(metadata.MaxMemory, 10)},
+		{"maxmemory-policy", metadata.MaxMemoryPolicy},
+		{"databases", strconv.Itoa(metadata.Databases)},
 	}
-	return []string{protocol.BuildArray(utility.ConvertStringArrayToAny(result))}
 }
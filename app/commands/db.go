@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+	"github.com/codecrafters-io/redis-starter-go/app/types"
+)
+
+// parseDBIndex parses a database index argument, errOnInvalid is returned
+// when it isn't a number at all
+func parseDBIndex(arg string, databases *storage.Databases, errOnInvalid error) (int, error) {
+	index, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, errOnInvalid
+	}
+	if !databases.IsValid(index) {
+		return 0, errors.New(protocol.DB_INDEX_OUT_OF_RANGE)
+	}
+	return index, nil
+}
+
+// validateFlushMode accepts the optional ASYNC/SYNC argument of FLUSHDB and
+// FLUSHALL. Both flush synchronously.
+func validateFlushMode(args []string) error {
+	if len(args) > 2 {
+		return errors.New(protocol.SYNTAX_ERROR)
+	}
+	if len(args) == 2 {
+		mode := strings.ToUpper(args[1])
+		if mode != "ASYNC" && mode != "SYNC" {
+			return errors.New(protocol.SYNTAX_ERROR)
+		}
+	}
+	return nil
+}
+
+// SelectCommand implements the SELECT command
+type SelectCommand struct {
+	databases *storage.Databases
+}
+
+// Execute implements Command.
+func (c *SelectCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return errExecuteUnreachable
+}
+
+// Validate implements Command.
+func (c *SelectCommand) Validate(args []string) error {
+	_, err := parseDBIndex(args[1], c.databases, errors.New(protocol.NOT_AN_INTEGER))
+	return err
+}
+
+// ExecuteWithClient switches the database the connection operates on
+func (c *SelectCommand) ExecuteWithClient(args []string, cache storage.Cache, metadata *types.ServerMetadata, client *Client) protocol.Reply {
+	index, _ := strconv.Atoi(args[1])
+	client.DB = index
+	return protocol.OK
+}
+
+// MoveCommand implements the MOVE command
+type MoveCommand struct {
+	databases *storage.Databases
+}
+
+// Execute implements Command.
+func (c *MoveCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return errExecuteUnreachable
+}
+
+// Validate implements Command.
+func (c *MoveCommand) Validate(args []string) error {
+	_, err := parseDBIndex(args[2], c.databases, errors.New(protocol.NOT_AN_INTEGER))
+	return err
+}
+
+// ExecuteWithClient moves the key out of the selected database
+func (c *MoveCommand) ExecuteWithClient(args []string, cache storage.Cache, metadata *types.ServerMetadata, client *Client) protocol.Reply {
+	dst, _ := strconv.Atoi(args[2])
+	if dst == client.DB {
+		return protocol.NewError("source and destination objects are the same")
+	}
+	if c.databases.Move(args[1], client.DB, dst, client.IsMaster) {
+		// Clients of the destination may be blocked on the key
+		client.signalKeyAsReady(dst, args[1])
+		return protocol.Integer(1)
+	}
+	return protocol.Integer(0)
+}
+
+// SwapDBCommand implements the SWAPDB command
+type SwapDBCommand struct {
+	databases *storage.Databases
+	blocked   *BlockedClients
+}
+
+// Execute implements Command.
+func (c *SwapDBCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return errExecuteUnreachable
+}
+
+// ExecuteWithClient swaps the databases, the keys clients are blocked on may
+// exist in their database from now on
+func (c *SwapDBCommand) ExecuteWithClient(args []string, cache storage.Cache, metadata *types.ServerMetadata, client *Client) protocol.Reply {
+	a, _ := strconv.Atoi(args[1])
+	b, _ := strconv.Atoi(args[2])
+	c.databases.Swap(a, b)
+	c.blocked.scanDatabaseForReadyKeys(client, a)
+	if b != a {
+		c.blocked.scanDatabaseForReadyKeys(client, b)
+	}
+	return protocol.OK
+}
+
+// Validate implements Command.
+func (c *SwapDBCommand) Validate(args []string) error {
+	if _, err := parseDBIndex(args[1], c.databases, errors.New("invalid first DB index")); err != nil {
+		return err
+	}
+	_, err := parseDBIndex(args[2], c.databases, errors.New("invalid second DB index"))
+	return err
+}
+
+// DBSizeCommand implements the DBSIZE command
+type DBSizeCommand struct{}
+
+// Execute implements Command.
+func (c *DBSizeCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return protocol.Integer(cache.Size())
+}
+
+// Validate implements Command.
+func (c *DBSizeCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// FlushDBCommand implements the FLUSHDB command
+type FlushDBCommand struct{}
+
+// Execute implements Command.
+func (c *FlushDBCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	cache.Flush()
+	return protocol.OK
+}
+
+// Validate implements Command.
+func (c *FlushDBCommand) Validate(args []string) error {
+	return validateFlushMode(args)
+}
+
+// FlushAllCommand implements the FLUSHALL command
+type FlushAllCommand struct {
+	databases *storage.Databases
+}
+
+// Execute implements Command.
+func (c *FlushAllCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	c.databases.FlushAll()
+	return protocol.OK
+}
+
+// Validate implements Command.
+func (c *FlushAllCommand) Validate(args []string) error {
+	return validateFlushMode(args)
+}
@@ -0,0 +1,200 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+)
+
+// expireOptions are the NX, XX, GT and LT conditions of the EXPIRE family
+type expireOptions struct {
+	nx, xx, gt, lt bool
+}
+
+func parseExpireOptions(args []string) (expireOptions, error) {
+	var opts expireOptions
+	for _, arg := range args {
+		switch strings.ToUpper(arg) {
+		case "NX":
+			opts.nx = true
+		case "XX":
+			opts.xx = true
+		case "GT":
+			opts.gt = true
+		case "LT":
+			opts.lt = true
+		default:
+			return opts, fmt.Errorf("Unsupported option %s", arg)
+		}
+	}
+
+	if opts.nx && (opts.xx || opts.gt || opts.lt) {
+		return opts, errors.New("NX and XX, GT or LT options at the same time are not compatible")
+	}
+	if opts.gt && opts.lt {
+		return opts, errors.New("GT and LT options at the same time are not compatible")
+	}
+	return opts, nil
+}
+
+// allows reports whether the conditions let a key currently expiring at
+// current (never when not volatile) get the new expiration
+func (o expireOptions) allows(current time.Time, volatile bool, expiresAt time.Time) bool {
+	switch {
+	case o.nx && volatile:
+		return false
+	case o.xx && !volatile:
+		return false
+	case o.gt && (!volatile || !expiresAt.After(current)):
+		// A persistent key counts as an infinite time to live
+		return false
+	case o.lt && volatile && !expiresAt.Before(current):
+		return false
+	}
+	return true
+}
+
+// ExpireCommand implements EXPIRE, PEXPIRE, EXPIREAT and PEXPIREAT, which only
+// differ in the unit of their time argument and whether it is relative to now
+type ExpireCommand struct {
+	name     string
+	unit     time.Duration
+	absolute bool
+}
+
+// expiresAt converts the time argument to the absolute expiration time in
+// unix milliseconds
+func (c *ExpireCommand) expiresAt(arg string, now time.Time) (int64, error) {
+	value, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, errors.New(protocol.NOT_AN_INTEGER)
+	}
+
+	invalid := fmt.Errorf("invalid expire time in '%s' command", c.name)
+	factor := int64(c.unit / time.Millisecond)
+	if value > math.MaxInt64/factor || value < math.MinInt64/factor {
+		return 0, invalid
+	}
+	milliseconds := value * factor
+	if !c.absolute {
+		if milliseconds > math.MaxInt64-now.UnixMilli() {
+			return 0, invalid
+		}
+		milliseconds += now.UnixMilli()
+	}
+	return milliseconds, nil
+}
+
+// Execute implements Command.
+func (c *ExpireCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return c.ExecuteAt(args, cache, time.Now())
+}
+
+// ExecuteAt implements TimedCommand.
+func (c *ExpireCommand) ExecuteAt(args []string, cache storage.Cache, now time.Time) protocol.Reply {
+	key := args[1]
+	milliseconds, err := c.expiresAt(args[2], now)
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	opts, err := parseExpireOptions(args[3:])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+
+	if _, exists := cache.Get(key); !exists {
+		return protocol.Integer(0)
+	}
+
+	expiresAt := time.UnixMilli(milliseconds)
+	current, volatile := cache.GetExpiration(key)
+	if !opts.allows(current, volatile, expiresAt) {
+		return protocol.Integer(0)
+	}
+
+	// An expiration in the past deletes the key right away
+	if !expiresAt.After(now) {
+		cache.Delete(key)
+		return protocol.Integer(1)
+	}
+	cache.SetExpiration(key, expiresAt)
+	return protocol.Integer(1)
+}
+
+// Validate implements Command.
+func (c *ExpireCommand) Validate(args []string) error {
+	if _, err := c.expiresAt(args[2], time.Now()); err != nil {
+		return err
+	}
+	_, err := parseExpireOptions(args[3:])
+	return err
+}
+
+// PropagateAt implements TimedCommand. Replicas receive the absolute
+// expiration in milliseconds so a delayed replication stream doesn't extend
+// the TTL, or a DEL when the key was deleted right away.
+func (c *ExpireCommand) PropagateAt(args []string, reply protocol.Reply, now time.Time) [][]string {
+	if reply != protocol.Integer(1) {
+		return nil
+	}
+	milliseconds, _ := c.expiresAt(args[2], now)
+	if milliseconds <= now.UnixMilli() {
+		return [][]string{{"DEL", args[1]}}
+	}
+	return [][]string{{"PEXPIREAT", args[1], strconv.FormatInt(milliseconds, 10)}}
+}
+
+// TTLCommand implements TTL, PTTL, EXPIRETIME and PEXPIRETIME
+type TTLCommand struct {
+	unit     time.Duration
+	absolute bool
+}
+
+// Execute implements Command.
+func (c *TTLCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	key := args[1]
+	if _, exists := cache.Get(key); !exists {
+		return protocol.Integer(-2)
+	}
+	expiresAt, volatile := cache.GetExpiration(key)
+	if !volatile {
+		return protocol.Integer(-1)
+	}
+
+	factor := int64(c.unit / time.Millisecond)
+	if c.absolute {
+		return protocol.Integer(expiresAt.UnixMilli() / factor)
+	}
+	remaining := max(time.Until(expiresAt).Milliseconds(), 0)
+	// Round to the closest unit, like Redis does for TTL
+	return protocol.Integer((remaining + factor/2) / factor)
+}
+
+// Validate implements Command.
+func (c *TTLCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// PersistCommand implements the PERSIST command
+type PersistCommand struct{}
+
+// Execute implements Command.
+func (c *PersistCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	if cache.Persist(args[1]) {
+		return protocol.Integer(1)
+	}
+	return protocol.Integer(0)
+}
+
+// Validate implements Command.
+func (c *PersistCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
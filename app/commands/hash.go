@@ -0,0 +1,465 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+	"github.com/codecrafters-io/redis-starter-go/app/utility"
+)
+
+// getHash returns the hash stored at key, nil when the key doesn't exist
+func getHash(cache storage.Cache, key string) (*storage.HashValue, error) {
+	redisValue, exists := cache.Get(key)
+	if !exists {
+		return nil, nil
+	}
+	hashValue, ok := redisValue.(*storage.HashValue)
+	if !ok {
+		return nil, protocol.ErrWrongType
+	}
+	return hashValue, nil
+}
+
+// getOrCreateHash returns the hash stored at key, or a new empty one when the
+// key doesn't exist. A new hash isn't stored yet, created tells the caller to
+// store it once the command succeeded so a failure leaves no empty hash
+// behind. Existing hashes are updated in place so they keep their expiration.
+func getOrCreateHash(cache storage.Cache, key string) (hashValue *storage.HashValue, created bool, err error) {
+	hashValue, err = getHash(cache, key)
+	if err != nil || hashValue != nil {
+		return hashValue, false, err
+	}
+	return storage.NewHashValue(), true, nil
+}
+
+// hashFieldsMap converts fields into a map reply
+func hashFieldsMap(fields []storage.HashField) protocol.Map {
+	reply := make(protocol.Map, 0, len(fields))
+	for _, field := range fields {
+		reply = append(reply, protocol.MapEntry{
+			Key:   protocol.BulkString(field.Field),
+			Value: protocol.BulkString(field.Value),
+		})
+	}
+	return reply
+}
+
+// HSetCommand implements the HSET and HMSET commands
+type HSetCommand struct {
+	name string
+}
+
+// Execute implements Command.
+func (c *HSetCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	hashValue, created, err := getOrCreateHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	added := 0
+	for i := 2; i < len(args); i += 2 {
+		if hashValue.Set(args[i], args[i+1]) {
+			added++
+		}
+	}
+	if created {
+		cache.Set(args[1], hashValue)
+	}
+	if c.name == "hmset" {
+		return protocol.OK
+	}
+	return protocol.Integer(added)
+}
+
+// Validate implements Command.
+func (c *HSetCommand) Validate(args []string) error {
+	if len(args)%2 != 0 {
+		return fmt.Errorf("wrong number of arguments for '%s' command", c.name)
+	}
+	return nil
+}
+
+// HSetNXCommand implements the HSETNX command
+type HSetNXCommand struct{}
+
+// Execute implements Command.
+func (c *HSetNXCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	hashValue, created, err := getOrCreateHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if !hashValue.SetNX(args[2], args[3]) {
+		return protocol.Integer(0)
+	}
+	if created {
+		cache.Set(args[1], hashValue)
+	}
+	return protocol.Integer(1)
+}
+
+// Validate implements Command.
+func (c *HSetNXCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// HGetCommand implements the HGET command
+type HGetCommand struct{}
+
+// Execute implements Command.
+func (c *HGetCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if hashValue == nil {
+		return protocol.NullBulkString{}
+	}
+	value, exists := hashValue.Get(args[2])
+	if !exists {
+		return protocol.NullBulkString{}
+	}
+	return protocol.BulkString(value)
+}
+
+// Validate implements Command.
+func (c *HGetCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// HMGetCommand implements the HMGET command
+type HMGetCommand struct{}
+
+// Execute implements Command.
+func (c *HMGetCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	reply := make(protocol.Array, 0, len(args)-2)
+	for _, field := range args[2:] {
+		if hashValue == nil {
+			reply = append(reply, protocol.NullBulkString{})
+			continue
+		}
+		value, exists := hashValue.Get(field)
+		if !exists {
+			reply = append(reply, protocol.NullBulkString{})
+			continue
+		}
+		reply = append(reply, protocol.BulkString(value))
+	}
+	return reply
+}
+
+// Validate implements Command.
+func (c *HMGetCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// HGetAllCommand implements the HGETALL, HKEYS and HVALS commands
+type HGetAllCommand struct {
+	keys   bool
+	values bool
+}
+
+// Execute implements Command.
+func (c *HGetAllCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	var fields []storage.HashField
+	if hashValue != nil {
+		fields = hashValue.Entries()
+	}
+	if c.keys && c.values {
+		return hashFieldsMap(fields)
+	}
+	reply := make(protocol.Array, 0, len(fields))
+	for _, field := range fields {
+		if c.keys {
+			reply = append(reply, protocol.BulkString(field.Field))
+		} else {
+			reply = append(reply, protocol.BulkString(field.Value))
+		}
+	}
+	return reply
+}
+
+// Validate implements Command.
+func (c *HGetAllCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// HDelCommand implements the HDEL command
+type HDelCommand struct{}
+
+// Execute implements Command.
+func (c *HDelCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if hashValue == nil {
+		return protocol.Integer(0)
+	}
+	deleted := hashValue.Delete(args[2:]...)
+	// Like Redis, a hash that has been emptied stops existing
+	if hashValue.Len() == 0 {
+		cache.Delete(args[1])
+	}
+	return protocol.Integer(deleted)
+}
+
+// Validate implements Command.
+func (c *HDelCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// HExistsCommand implements the HEXISTS command
+type HExistsCommand struct{}
+
+// Execute implements Command.
+func (c *HExistsCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if hashValue == nil {
+		return protocol.Integer(0)
+	}
+	if _, exists := hashValue.Get(args[2]); exists {
+		return protocol.Integer(1)
+	}
+	return protocol.Integer(0)
+}
+
+// Validate implements Command.
+func (c *HExistsCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// HLenCommand implements the HLEN command
+type HLenCommand struct{}
+
+// Execute implements Command.
+func (c *HLenCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if hashValue == nil {
+		return protocol.Integer(0)
+	}
+	return protocol.Integer(hashValue.Len())
+}
+
+// Validate implements Command.
+func (c *HLenCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// HStrLenCommand implements the HSTRLEN command
+type HStrLenCommand struct{}
+
+// Execute implements Command.
+func (c *HStrLenCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if hashValue == nil {
+		return protocol.Integer(0)
+	}
+	value, _ := hashValue.Get(args[2])
+	return protocol.Integer(len(value))
+}
+
+// Validate implements Command.
+func (c *HStrLenCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// HIncrByCommand implements the HINCRBY command
+type HIncrByCommand struct{}
+
+// Execute implements Command.
+func (c *HIncrByCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	delta, _ := strconv.ParseInt(args[3], 10, 64)
+	hashValue, created, err := getOrCreateHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	value, err := hashValue.IncrBy(args[2], delta)
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if created {
+		cache.Set(args[1], hashValue)
+	}
+	return protocol.Integer(value)
+}
+
+// Validate implements Command.
+func (c *HIncrByCommand) Validate(args []string) error {
+	if _, err := strconv.ParseInt(args[3], 10, 64); err != nil {
+		return errors.New(protocol.NOT_AN_INTEGER)
+	}
+	return nil
+}
+
+// HIncrByFloatCommand implements the HINCRBYFLOAT command
+type HIncrByFloatCommand struct{}
+
+// Execute implements Command.
+func (c *HIncrByFloatCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	delta, _ := utility.ParseFloat(args[3])
+	hashValue, created, err := getOrCreateHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	value, err := hashValue.IncrByFloat(args[2], delta)
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if created {
+		cache.Set(args[1], hashValue)
+	}
+	return protocol.BulkString(value)
+}
+
+// Validate implements Command.
+func (c *HIncrByFloatCommand) Validate(args []string) error {
+	delta, err := utility.ParseFloat(args[3])
+	if err != nil {
+		return errors.New("value is not a valid float")
+	}
+	if math.IsInf(delta, 0) {
+		return errors.New("increment would produce NaN or Infinity")
+	}
+	return nil
+}
+
+// Propagate implements Propagator, replicas store the resulting value since
+// floating point additions may round differently on another machine. HSET
+// would clear the expiration of the field, KEEPTTL keeps it like the
+// increment did.
+func (c *HIncrByFloatCommand) Propagate(args []string, reply protocol.Reply) [][]string {
+	value, ok := reply.(protocol.BulkString)
+	if !ok {
+		return nil
+	}
+	return [][]string{{"HSETEX", args[1], "KEEPTTL", "FIELDS", "1", args[2], string(value)}}
+}
+
+// maxRandomRepeats bounds the negative count of the commands returning random
+// elements. Repeated elements aren't limited by the size of the value, and
+// the whole reply is built before being written.
+const maxRandomRepeats = 1 << 20
+
+// HRandFieldCommand implements the HRANDFIELD command
+type HRandFieldCommand struct{}
+
+// Execute implements Command.
+func (c *HRandFieldCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+
+	if len(args) == 2 {
+		if hashValue == nil || hashValue.Len() == 0 {
+			return protocol.NullBulkString{}
+		}
+		return protocol.BulkString(hashValue.Random(1, false)[0].Field)
+	}
+
+	count, _ := strconv.ParseInt(args[2], 10, 64)
+	withValues := len(args) == 4
+	if hashValue == nil || count == 0 {
+		return protocol.Array{}
+	}
+	// A negative count allows the same field to be returned several times
+	var fields []storage.HashField
+	if count < 0 {
+		fields = hashValue.Random(int(-count), true)
+	} else {
+		fields = hashValue.Random(int(count), false)
+	}
+
+	if withValues {
+		return protocol.Pairs(hashFieldsMap(fields))
+	}
+	reply := make(protocol.Array, 0, len(fields))
+	for _, field := range fields {
+		reply = append(reply, protocol.BulkString(field.Field))
+	}
+	return reply
+}
+
+// Validate implements Command.
+func (c *HRandFieldCommand) Validate(args []string) error {
+	if len(args) > 4 {
+		return errors.New(protocol.SYNTAX_ERROR)
+	}
+	if len(args) >= 3 {
+		count, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return errors.New(protocol.NOT_AN_INTEGER)
+		}
+		// Repeated fields are all materialized, keep them bounded
+		if count < -maxRandomRepeats {
+			return errors.New("value is out of range")
+		}
+	}
+	if len(args) == 4 && strings.ToUpper(args[3]) != "WITHVALUES" {
+		return errors.New(protocol.SYNTAX_ERROR)
+	}
+	return nil
+}
+
+// HScanCommand implements the HSCAN command
+type HScanCommand struct{}
+
+// Execute implements Command.
+func (c *HScanCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	options, _ := parseScanOptions(args[2:], scanAllowNoValues)
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if hashValue == nil {
+		return scanReply(0, nil)
+	}
+
+	cursor, fields := hashValue.Scan(options.cursor, options.count)
+	elements := make([]string, 0, len(fields)*2)
+	for _, field := range fields {
+		if !options.matches(field.Field) {
+			continue
+		}
+		elements = append(elements, field.Field)
+		if !options.noValues {
+			elements = append(elements, field.Value)
+		}
+	}
+	return scanReply(cursor, elements)
+}
+
+// Validate implements Command.
+func (c *HScanCommand) Validate(args []string) error {
+	_, err := parseScanOptions(args[2:], scanAllowNoValues)
+	return err
+}
@@ -0,0 +1,525 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+)
+
+// hashExpireTimeMax is the latest expiration time of a field, in unix
+// milliseconds, like Redis
+const hashExpireTimeMax = 1 << 48
+
+// Results of setting the expiration of a field, as replied per field
+const (
+	fieldNotFound      = -2
+	fieldNoTTL         = -1
+	fieldConditionFail = 0
+	fieldExpireSet     = 1
+	fieldDeleted       = 2
+)
+
+// parseFields parses the "FIELDS numfields field..." block starting at
+// args[at], perField is how many arguments each field takes. It returns the
+// arguments of the fields.
+func parseFields(args []string, at int, perField int) ([]string, error) {
+	if at >= len(args) || strings.ToUpper(args[at]) != "FIELDS" {
+		return nil, errors.New("Mandatory argument FIELDS is missing or not at the right position")
+	}
+	if at+1 >= len(args) {
+		return nil, errors.New(protocol.SYNTAX_ERROR)
+	}
+	count, err := strconv.Atoi(args[at+1])
+	if err != nil || count <= 0 {
+		return nil, errors.New("Number of fields must be a positive integer")
+	}
+	if len(args)-at-2 != count*perField {
+		return nil, errors.New("The `numfields` parameter must match the number of arguments")
+	}
+	return args[at+2:], nil
+}
+
+// fieldsArgs builds a "FIELDS numfields field..." block
+func fieldsArgs(fields []string) []string {
+	return append([]string{"FIELDS", strconv.Itoa(len(fields))}, fields...)
+}
+
+// hashExpiresAt converts the time argument of a field expiration to unix
+// milliseconds, unit is the unit of the argument and absolute tells whether
+// it is a timestamp rather than relative to now
+func hashExpiresAt(name, arg string, unit time.Duration, absolute bool, now time.Time) (int64, error) {
+	value, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, errors.New(protocol.NOT_AN_INTEGER)
+	}
+	factor := int64(unit / time.Millisecond)
+	if value < 0 || value > hashExpireTimeMax/factor {
+		return 0, errors.New("invalid expire time, must be >= 0 && <= 2^48")
+	}
+	milliseconds := value * factor
+	if !absolute {
+		milliseconds += now.UnixMilli()
+	}
+	if milliseconds > hashExpireTimeMax {
+		return 0, fmt.Errorf("invalid expire time in '%s' command", name)
+	}
+	return milliseconds, nil
+}
+
+// deleteEmptyHash removes the key of a hash that lost its last field
+func deleteEmptyHash(cache storage.Cache, key string, hashValue *storage.HashValue) {
+	if hashValue.Len() == 0 {
+		cache.Delete(key)
+	}
+}
+
+// HExpireCommand implements HEXPIRE, HPEXPIRE, HEXPIREAT and HPEXPIREAT,
+// which only differ in the unit of their time argument and whether it is
+// relative to now
+type HExpireCommand struct {
+	name     string
+	unit     time.Duration
+	absolute bool
+}
+
+// parse returns the expiration time, the condition and the fields
+func (c *HExpireCommand) parse(args []string, now time.Time) (int64, expireOptions, []string, error) {
+	milliseconds, err := hashExpiresAt(c.name, args[2], c.unit, c.absolute, now)
+	if err != nil {
+		return 0, expireOptions{}, nil, err
+	}
+	at := 3
+	var opts expireOptions
+	if strings.ToUpper(args[3]) != "FIELDS" {
+		if opts, err = parseExpireOptions(args[3:4]); err != nil {
+			return 0, expireOptions{}, nil, err
+		}
+		at = 4
+	}
+	fields, err := parseFields(args, at, 1)
+	return milliseconds, opts, fields, err
+}
+
+// Execute implements Command.
+func (c *HExpireCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return c.ExecuteAt(args, cache, time.Now())
+}
+
+// ExecuteAt implements TimedCommand.
+func (c *HExpireCommand) ExecuteAt(args []string, cache storage.Cache, now time.Time) protocol.Reply {
+	milliseconds, opts, fields, err := c.parse(args, now)
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+
+	reply := make(protocol.Array, 0, len(fields))
+	expiresAt := time.UnixMilli(milliseconds)
+	for _, field := range fields {
+		if hashValue == nil {
+			reply = append(reply, protocol.Integer(fieldNotFound))
+			continue
+		}
+		current, volatile, exists := hashValue.FieldExpiration(field)
+		switch {
+		case !exists:
+			reply = append(reply, protocol.Integer(fieldNotFound))
+		case !opts.allows(current, volatile, expiresAt):
+			reply = append(reply, protocol.Integer(fieldConditionFail))
+		case !expiresAt.After(now):
+			// An expiration in the past deletes the field right away
+			hashValue.Delete(field)
+			reply = append(reply, protocol.Integer(fieldDeleted))
+		default:
+			hashValue.SetFieldExpiration(field, expiresAt)
+			reply = append(reply, protocol.Integer(fieldExpireSet))
+		}
+	}
+	if hashValue != nil {
+		deleteEmptyHash(cache, args[1], hashValue)
+	}
+	return reply
+}
+
+// Validate implements Command.
+func (c *HExpireCommand) Validate(args []string) error {
+	_, _, _, err := c.parse(args, time.Now())
+	return err
+}
+
+// PropagateAt implements TimedCommand. Replicas receive the absolute expiration
+// in milliseconds of the fields it was set on, and a HDEL of the fields that
+// were deleted right away.
+func (c *HExpireCommand) PropagateAt(args []string, reply protocol.Reply, now time.Time) [][]string {
+	results, ok := reply.(protocol.Array)
+	if !ok {
+		return nil
+	}
+	milliseconds, _, fields, _ := c.parse(args, now)
+	return propagateFieldResults(args[1], fields, results, []string{"HPEXPIREAT", args[1], strconv.FormatInt(milliseconds, 10)})
+}
+
+// propagateFieldResults replicates the per field results of a command as
+// expire, the command prefix applied to the fields whose expiration was set,
+// and a HDEL of the deleted fields
+func propagateFieldResults(key string, fields []string, results protocol.Array, expire []string) [][]string {
+	expired, deleted := make([]string, 0), make([]string, 0)
+	for i, result := range results {
+		switch result {
+		case protocol.Integer(fieldExpireSet):
+			expired = append(expired, fields[i])
+		case protocol.Integer(fieldDeleted):
+			deleted = append(deleted, fields[i])
+		}
+	}
+	propagated := make([][]string, 0, 2)
+	if len(expired) > 0 {
+		propagated = append(propagated, append(expire, fieldsArgs(expired)...))
+	}
+	if len(deleted) > 0 {
+		propagated = append(propagated, append([]string{"HDEL", key}, deleted...))
+	}
+	return propagated
+}
+
+// HTTLCommand implements HTTL, HPTTL, HEXPIRETIME and HPEXPIRETIME
+type HTTLCommand struct {
+	unit     time.Duration
+	absolute bool
+}
+
+// Execute implements Command.
+func (c *HTTLCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	fields, _ := parseFields(args, 2, 1)
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+
+	factor := int64(c.unit / time.Millisecond)
+	reply := make(protocol.Array, 0, len(fields))
+	for _, field := range fields {
+		if hashValue == nil {
+			reply = append(reply, protocol.Integer(fieldNotFound))
+			continue
+		}
+		expiresAt, volatile, exists := hashValue.FieldExpiration(field)
+		switch {
+		case !exists:
+			reply = append(reply, protocol.Integer(fieldNotFound))
+		case !volatile:
+			reply = append(reply, protocol.Integer(fieldNoTTL))
+		case c.absolute:
+			reply = append(reply, protocol.Integer(expiresAt.UnixMilli()/factor))
+		default:
+			remaining := max(time.Until(expiresAt).Milliseconds(), 0)
+			// Round to the closest unit, like TTL
+			reply = append(reply, protocol.Integer((remaining+factor/2)/factor))
+		}
+	}
+	return reply
+}
+
+// Validate implements Command.
+func (c *HTTLCommand) Validate(args []string) error {
+	_, err := parseFields(args, 2, 1)
+	return err
+}
+
+// HPersistCommand implements the HPERSIST command
+type HPersistCommand struct{}
+
+// Execute implements Command.
+func (c *HPersistCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	fields, _ := parseFields(args, 2, 1)
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+
+	reply := make(protocol.Array, 0, len(fields))
+	for _, field := range fields {
+		if hashValue == nil {
+			reply = append(reply, protocol.Integer(fieldNotFound))
+			continue
+		}
+		if _, _, exists := hashValue.FieldExpiration(field); !exists {
+			reply = append(reply, protocol.Integer(fieldNotFound))
+		} else if hashValue.PersistField(field) {
+			reply = append(reply, protocol.Integer(1))
+		} else {
+			reply = append(reply, protocol.Integer(fieldNoTTL))
+		}
+	}
+	return reply
+}
+
+// Validate implements Command.
+func (c *HPersistCommand) Validate(args []string) error {
+	_, err := parseFields(args, 2, 1)
+	return err
+}
+
+// fieldExpiry is the expiration option of HGETEX and HSETEX
+type fieldExpiry struct {
+	// expiresAt is the expiration time in unix milliseconds, 0 when the
+	// command doesn't set one
+	expiresAt int64
+	persist   bool
+	keepTTL   bool
+}
+
+// parseFieldExpiry parses the EX, PX, EXAT and PXAT options along with
+// PERSIST or KEEPTTL, whichever is given in allowed, at args[i]. It reports
+// how many arguments the option took, 0 when args[i] isn't one of them.
+func parseFieldExpiry(name string, args []string, i int, allowed string, now time.Time) (fieldExpiry, int, error) {
+	option := strings.ToUpper(args[i])
+	switch option {
+	case allowed:
+		return fieldExpiry{persist: option == "PERSIST", keepTTL: option == "KEEPTTL"}, 1, nil
+	case "EX", "PX", "EXAT", "PXAT":
+		if i+1 >= len(args) {
+			return fieldExpiry{}, 0, errors.New(protocol.SYNTAX_ERROR)
+		}
+		unit := time.Millisecond
+		if option == "EX" || option == "EXAT" {
+			unit = time.Second
+		}
+		absolute := option == "EXAT" || option == "PXAT"
+		milliseconds, err := hashExpiresAt(name, args[i+1], unit, absolute, now)
+		return fieldExpiry{expiresAt: milliseconds}, 2, err
+	}
+	return fieldExpiry{}, 0, nil
+}
+
+// apply sets the expiration of a field that was just read or written, an
+// expiration in the past deletes the field
+func (e fieldExpiry) apply(hashValue *storage.HashValue, field string, now time.Time) {
+	switch {
+	case e.persist:
+		hashValue.PersistField(field)
+	case e.expiresAt == 0:
+		// The field keeps its expiration
+	case e.expiresAt <= now.UnixMilli():
+		hashValue.Delete(field)
+	default:
+		hashValue.SetFieldExpiration(field, time.UnixMilli(e.expiresAt))
+	}
+}
+
+// HGetExCommand implements the HGETEX command
+type HGetExCommand struct{}
+
+func (c *HGetExCommand) parse(args []string, now time.Time) (fieldExpiry, []string, error) {
+	expiry, used, err := parseFieldExpiry("hgetex", args, 2, "PERSIST", now)
+	if err != nil {
+		return fieldExpiry{}, nil, err
+	}
+	fields, err := parseFields(args, 2+used, 1)
+	return expiry, fields, err
+}
+
+// Execute implements Command.
+func (c *HGetExCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return c.ExecuteAt(args, cache, time.Now())
+}
+
+// ExecuteAt implements TimedCommand.
+func (c *HGetExCommand) ExecuteAt(args []string, cache storage.Cache, now time.Time) protocol.Reply {
+	expiry, fields, err := c.parse(args, now)
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+
+	reply := make(protocol.Array, 0, len(fields))
+	for _, field := range fields {
+		if hashValue == nil {
+			reply = append(reply, protocol.NullBulkString{})
+			continue
+		}
+		value, exists := hashValue.Get(field)
+		if !exists {
+			reply = append(reply, protocol.NullBulkString{})
+			continue
+		}
+		expiry.apply(hashValue, field, now)
+		reply = append(reply, protocol.BulkString(value))
+	}
+	if hashValue != nil {
+		deleteEmptyHash(cache, args[1], hashValue)
+	}
+	return reply
+}
+
+// Validate implements Command.
+func (c *HGetExCommand) Validate(args []string) error {
+	_, _, err := c.parse(args, time.Now())
+	return err
+}
+
+// PropagateAt implements TimedCommand, the expiration of the fields that were
+// read is replicated like HPEXPIREAT and HPERSIST would
+func (c *HGetExCommand) PropagateAt(args []string, reply protocol.Reply, now time.Time) [][]string {
+	values, ok := reply.(protocol.Array)
+	if !ok {
+		return nil
+	}
+	expiry, fields, _ := c.parse(args, now)
+	if !expiry.persist && expiry.expiresAt == 0 {
+		return nil
+	}
+	read := make([]string, 0, len(fields))
+	for i, value := range values {
+		if _, missing := value.(protocol.NullBulkString); !missing {
+			read = append(read, fields[i])
+		}
+	}
+	if len(read) == 0 {
+		return nil
+	}
+	switch {
+	case expiry.persist:
+		return [][]string{append([]string{"HPERSIST", args[1]}, fieldsArgs(read)...)}
+	case expiry.expiresAt <= now.UnixMilli():
+		return [][]string{append([]string{"HDEL", args[1]}, read...)}
+	}
+	return [][]string{append([]string{"HPEXPIREAT", args[1], strconv.FormatInt(expiry.expiresAt, 10)}, fieldsArgs(read)...)}
+}
+
+// HSetExCommand implements the HSETEX command
+type HSetExCommand struct{}
+
+// hsetexOptions holds the parsed arguments of HSETEX
+type hsetexOptions struct {
+	fnx, fxx bool
+	expiry   fieldExpiry
+	// pairs holds the fields and their values
+	pairs []string
+}
+
+func (c *HSetExCommand) parse(args []string, now time.Time) (hsetexOptions, error) {
+	var opts hsetexOptions
+	i := 2
+	expirySet := false
+	for i < len(args) && strings.ToUpper(args[i]) != "FIELDS" {
+		switch strings.ToUpper(args[i]) {
+		case "FNX":
+			opts.fnx = true
+			i++
+			continue
+		case "FXX":
+			opts.fxx = true
+			i++
+			continue
+		}
+		expiry, used, err := parseFieldExpiry("hsetex", args, i, "KEEPTTL", now)
+		if err != nil {
+			return opts, err
+		}
+		if used == 0 || expirySet {
+			return opts, errors.New(protocol.SYNTAX_ERROR)
+		}
+		opts.expiry, expirySet = expiry, true
+		i += used
+	}
+	if opts.fnx && opts.fxx {
+		return opts, errors.New(protocol.SYNTAX_ERROR)
+	}
+	pairs, err := parseFields(args, i, 2)
+	opts.pairs = pairs
+	return opts, err
+}
+
+// Execute implements Command.
+func (c *HSetExCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return c.ExecuteAt(args, cache, time.Now())
+}
+
+// ExecuteAt implements TimedCommand.
+func (c *HSetExCommand) ExecuteAt(args []string, cache storage.Cache, now time.Time) protocol.Reply {
+	opts, err := c.parse(args, now)
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	hashValue, err := getHash(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+
+	// FNX and FXX apply to all the fields at once
+	for i := 0; i < len(opts.pairs); i += 2 {
+		exists := false
+		if hashValue != nil {
+			_, exists = hashValue.Get(opts.pairs[i])
+		}
+		if (opts.fnx && exists) || (opts.fxx && !exists) {
+			return protocol.Integer(0)
+		}
+	}
+	created := hashValue == nil
+	if created {
+		hashValue = storage.NewHashValue()
+	}
+
+	for i := 0; i < len(opts.pairs); i += 2 {
+		field, value := opts.pairs[i], opts.pairs[i+1]
+		expiresAt, volatile, _ := hashValue.FieldExpiration(field)
+		hashValue.Set(field, value)
+		if opts.expiry.keepTTL && volatile {
+			hashValue.SetFieldExpiration(field, expiresAt)
+			continue
+		}
+		opts.expiry.apply(hashValue, field, now)
+	}
+	// Fields that expired right away may leave the hash empty
+	if created && hashValue.Len() > 0 {
+		cache.Set(args[1], hashValue)
+	}
+	deleteEmptyHash(cache, args[1], hashValue)
+	return protocol.Integer(1)
+}
+
+// Validate implements Command.
+func (c *HSetExCommand) Validate(args []string) error {
+	_, err := c.parse(args, time.Now())
+	return err
+}
+
+// PropagateAt implements TimedCommand. Replicas receive the expiration as an
+// absolute PXAT, or a HDEL when the fields expired right away, and no FNX or
+// FXX since the master already checked them.
+func (c *HSetExCommand) PropagateAt(args []string, reply protocol.Reply, now time.Time) [][]string {
+	if reply != protocol.Integer(1) {
+		return nil
+	}
+	opts, _ := c.parse(args, now)
+	fieldCount := strconv.Itoa(len(opts.pairs) / 2)
+	rewritten := []string{"HSETEX", args[1]}
+	switch {
+	case opts.expiry.keepTTL:
+		rewritten = append(rewritten, "KEEPTTL")
+	case opts.expiry.expiresAt == 0:
+	case opts.expiry.expiresAt <= now.UnixMilli():
+		fields := make([]string, 0, len(opts.pairs)/2)
+		for i := 0; i < len(opts.pairs); i += 2 {
+			fields = append(fields, opts.pairs[i])
+		}
+		return [][]string{append([]string{"HDEL", args[1]}, fields...)}
+	default:
+		rewritten = append(rewritten, "PXAT", strconv.FormatInt(opts.expiry.expiresAt, 10))
+	}
+	rewritten = append(rewritten, "FIELDS", fieldCount)
+	return [][]string{append(rewritten, opts.pairs...)}
+}
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+	"github.com/codecrafters-io/redis-starter-go/app/types"
+)
+
+const (
+	serverName    = "redis"
+	serverVersion = "7.4.0"
+)
+
+// HelloCommand implements HELLO [protover [AUTH username password] [SETNAME clientname]]
+type HelloCommand struct{}
+
+type helloOptions struct {
+	protocol   int
+	username   string
+	password   string
+	clientName string
+	hasAuth    bool
+	hasName    bool
+}
+
+// Execute implements Command.
+func (h *HelloCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return protocol.NewError("This method of `hello` shouldn't be called")
+}
+
+// Validate implements Command.
+func (h *HelloCommand) Validate(args []string) error {
+	_, err := h.parseOptions(args)
+	return err
+}
+
+func (h *HelloCommand) parseOptions(args []string) (*helloOptions, error) {
+	options := &helloOptions{}
+	if len(args) < 2 {
+		return options, nil
+	}
+
+	version, err := strconv.Atoi(args[1])
+	if err != nil {
+		return nil, errors.New("Protocol version is not an integer or out of range")
+	}
+	options.protocol = version
+
+	for i := 2; i < len(args); i++ {
+		remaining := len(args) - i - 1
+		switch strings.ToUpper(args[i]) {
+		case "AUTH":
+			if remaining < 2 {
+				return nil, errors.New("Syntax error in HELLO option 'auth'")
+			}
+			options.hasAuth = true
+			options.username = args[i+1]
+			options.password = args[i+2]
+			i += 2
+		case "SETNAME":
+			if remaining < 1 {
+				return nil, errors.New("Syntax error in HELLO option 'setname'")
+			}
+			options.hasName = true
+			options.clientName = args[i+1]
+			i++
+		default:
+			return nil, errors.New("Syntax error in HELLO option '" + args[i] + "'")
+		}
+	}
+	return options, nil
+}
+
+func (h *HelloCommand) ExecuteWithClient(args []string, cache storage.Cache, metadata *types.ServerMetadata, client *Client) protocol.Reply {
+	options, err := h.parseOptions(args)
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+
+	if options.protocol != 0 && options.protocol != protocol.RESP2 && options.protocol != protocol.RESP3 {
+		return protocol.ErrNoProto
+	}
+
+	// There is no ACL support yet, so only the passwordless default user exists
+	if options.hasAuth && options.username != "default" {
+		return protocol.ErrWrongPass
+	}
+
+	if options.hasName {
+		if !isValidClientName(options.clientName) {
+			return protocol.NewError("Client names cannot contain spaces, newlines or special characters.")
+		}
+		client.Name = options.clientName
+	}
+
+	if options.protocol != 0 {
+		client.Protocol = options.protocol
+	}
+
+	role := "master"
+	if metadata.Role == "slave" {
+		role = "replica"
+	}
+	// The connection encodes this with the newly negotiated protocol, a flat
+	// array for RESP2 and a map for RESP3
+	return protocol.Map{
+		{Key: protocol.BulkString("server"), Value: protocol.BulkString(serverName)},
+		{Key: protocol.BulkString("version"), Value: protocol.BulkString(serverVersion)},
+		{Key: protocol.BulkString("proto"), Value: protocol.Integer(client.Protocol)},
+		{Key: protocol.BulkString("id"), Value: protocol.Integer(client.ID)},
+		{Key: protocol.BulkString("mode"), Value: protocol.BulkString("standalone")},
+		{Key: protocol.BulkString("role"), Value: protocol.BulkString(role)},
+		{Key: protocol.BulkString("modules"), Value: protocol.Array{}},
+	}
+}
+
+func isValidClientName(name string) bool {
+	for _, c := range []byte(name) {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
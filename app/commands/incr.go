@@ -1,9 +1,6 @@
 package commands
 
 import (
-	"fmt"
-	"time"
-
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/storage"
 )
@@ -11,12 +8,12 @@ import (
 type IncrCommand struct{}
 
 // Execute implements Command.
-func (i *IncrCommand) Execute(args []string, cache storage.Cache) string {
+func (i *IncrCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
 	key := args[1]
 	redisValue, ok := cache.Get(key)
 	if !ok {
 		// Insert Key
-		intValue := storage.IntValue{Val: 0, Expiration: time.Now()}
+		intValue := storage.IntValue{Val: 0}
 		cache.Set(key, &intValue)
 	}
 
@@ -24,18 +21,18 @@ func (i *IncrCommand) Execute(args []string, cache storage.Cache) string {
 
 	value, isInteger := redisValue.(*storage.IntValue)
 	if !isInteger {
-		// TODO
-		return protocol.BuildError(protocol.NOT_AN_INTEGER)
+		if _, isString := redisValue.(*storage.StringValue); isString {
+			return protocol.NewError(protocol.NOT_AN_INTEGER)
+		}
+		return protocol.ErrWrongType
 	}
 	value.Val += 1
 
-	return protocol.BuildInt(value.Val)
+	return protocol.Integer(value.Val)
 }
 
 // Validate implements Command.
 func (i *IncrCommand) Validate(args []string) error {
-	if len(args) != 2 {
-		return fmt.Errorf("invalid number of arguments")
-	}
+	// Arity is checked by the registry
 	return nil
 }
@@ -2,29 +2,89 @@ package commands
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/storage"
 	"github.com/codecrafters-io/redis-starter-go/app/types"
+	"github.com/codecrafters-io/redis-starter-go/app/utility"
 )
 
 type InfoCommand struct {
+	databases *storage.Databases
+}
+
+// infoSection is a titled group of "field:value" lines of the INFO reply
+type infoSection struct {
+	name  string
+	lines func() []string
 }
 
 // Execute implements Command.
-func (i *InfoCommand) Execute(args []string, cache storage.Cache) string {
-	return ""
+func (i *InfoCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return protocol.NewError("This method of `info` shouldn't be called")
 }
 
 // Validate implements Command.
 func (i *InfoCommand) Validate(args []string) error {
-	if len(args) > 2 {
-		return fmt.Errorf("wrong number of arguments for 'info' command")
-	}
+	// Arity is checked by the registry
 	return nil
 }
 
-func (i *InfoCommand) ExecuteWithMetadata(args []string, cache storage.Cache, metadata *types.ServerMetadata) []string {
-	// convert server_metadata to string
-	return []string{protocol.BuildBulkString(metadata.String())}
+func (i *InfoCommand) ExecuteWithMetadata(args []string, cache storage.Cache, metadata *types.ServerMetadata) protocol.Reply {
+	sections := []infoSection{
+		// The replication lines come with their own "# Replication" title
+		{name: "replication", lines: metadata.ToStringArray},
+		{name: "memory", lines: func() []string { return i.memorySection(metadata) }},
+		{name: "stats", lines: i.statsSection},
+	}
+
+	requested := make(map[string]bool)
+	for _, arg := range args[1:] {
+		requested[strings.ToLower(arg)] = true
+	}
+	all := len(requested) == 0 || requested["all"] || requested["default"] || requested["everything"]
+
+	blocks := make([]string, 0, len(sections))
+	for _, section := range sections {
+		if all || requested[section.name] {
+			blocks = append(blocks, strings.Join(section.lines(), protocol.CRLF)+protocol.CRLF)
+		}
+	}
+	return protocol.Verbatim{Format: "txt", Text: strings.Join(blocks, protocol.CRLF)}
+}
+
+func (i *InfoCommand) memorySection(metadata *types.ServerMetadata) []string {
+	var used, dataset int64
+	for _, db := range i.databases.MemoryStats() {
+		used += db.Used
+		dataset += db.Dataset()
+	}
+	datasetPerc := 0.0
+	if used > 0 {
+		datasetPerc = float64(dataset) * 100 / float64(used)
+	}
+	return []string{
+		"# Memory",
+		fmt.Sprintf("used_memory:%d", used),
+		fmt.Sprintf("used_memory_human:%s", utility.BytesToHuman(used)),
+		fmt.Sprintf("used_memory_dataset:%d", dataset),
+		fmt.Sprintf("used_memory_dataset_perc:%.2f%%", datasetPerc),
+		fmt.Sprintf("used_memory_overhead:%d", used-dataset),
+		fmt.Sprintf("maxmemory:%d", metadata.MaxMemory),
+		fmt.Sprintf("maxmemory_human:%s", utility.BytesToHuman(metadata.MaxMemory)),
+		fmt.Sprintf("maxmemory_policy:%s", metadata.MaxMemoryPolicy),
+	}
+}
+
+func (i *InfoCommand) statsSection() []string {
+	stats := i.databases.ExpireStats()
+	return []string{
+		"# Stats",
+		fmt.Sprintf("expired_keys:%d", i.databases.ExpiredKeys()),
+		fmt.Sprintf("expired_subkeys:%d", i.databases.ExpiredFields()),
+		fmt.Sprintf("expired_stale_perc:%.2f", stats.StalePerc),
+		fmt.Sprintf("expired_time_cap_reached_count:%d", stats.TimeCapReached),
+		fmt.Sprintf("evicted_keys:%d", i.databases.EvictedKeys()),
+	}
 }
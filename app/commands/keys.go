@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+	"github.com/codecrafters-io/redis-starter-go/app/types"
+)
+
+// DelCommand implements the DEL and UNLINK commands. Values are never freed
+// synchronously, see storage.InMemoryCache.Delete, so both behave the same.
+type DelCommand struct{}
+
+// Execute implements Command.
+func (c *DelCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	deleted := 0
+	for _, key := range args[1:] {
+		// Logically expired keys kept by replicas are removed as well
+		if cache.Delete(key) {
+			deleted++
+		}
+	}
+	return protocol.Integer(deleted)
+}
+
+// Validate implements Command.
+func (c *DelCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// ExistsCommand implements the EXISTS command
+type ExistsCommand struct{}
+
+// Execute implements Command.
+func (c *ExistsCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	count := 0
+	// A key given several times is counted several times
+	for _, key := range args[1:] {
+		if cache.Exists(key) {
+			count++
+		}
+	}
+	return protocol.Integer(count)
+}
+
+// Validate implements Command.
+func (c *ExistsCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// TouchCommand implements the TOUCH command
+type TouchCommand struct{}
+
+// Execute implements Command.
+func (c *TouchCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	touched := 0
+	for _, key := range args[1:] {
+		// Get records the access
+		if _, exists := cache.Get(key); exists {
+			touched++
+		}
+	}
+	return protocol.Integer(touched)
+}
+
+// Validate implements Command.
+func (c *TouchCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// RenameCommand implements the RENAME and RENAMENX commands
+type RenameCommand struct {
+	nx bool
+}
+
+// Execute implements Command.
+func (c *RenameCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	renamed, err := cache.Rename(args[1], args[2], c.nx)
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if !c.nx {
+		return protocol.OK
+	}
+	if renamed {
+		return protocol.Integer(1)
+	}
+	return protocol.Integer(0)
+}
+
+// Validate implements Command.
+func (c *RenameCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// CopyCommand implements the COPY command
+type CopyCommand struct {
+	databases *storage.Databases
+}
+
+// copyOptions holds the optional arguments of COPY, db is -1 when the copy
+// stays in the selected database
+type copyOptions struct {
+	db      int
+	replace bool
+}
+
+func (c *CopyCommand) parseOptions(args []string) (copyOptions, error) {
+	options := copyOptions{db: -1}
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "DB":
+			if i+1 >= len(args) {
+				return copyOptions{}, errors.New(protocol.SYNTAX_ERROR)
+			}
+			db, err := parseDBIndex(args[i+1], c.databases, errors.New(protocol.NOT_AN_INTEGER))
+			if err != nil {
+				return copyOptions{}, err
+			}
+			options.db = db
+			i++
+		case "REPLACE":
+			options.replace = true
+		default:
+			return copyOptions{}, errors.New(protocol.SYNTAX_ERROR)
+		}
+	}
+	return options, nil
+}
+
+// Execute implements Command.
+func (c *CopyCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return errExecuteUnreachable
+}
+
+// Validate implements Command.
+func (c *CopyCommand) Validate(args []string) error {
+	_, err := c.parseOptions(args)
+	return err
+}
+
+// ExecuteWithClient copies the key, possibly to another database
+func (c *CopyCommand) ExecuteWithClient(args []string, cache storage.Cache, metadata *types.ServerMetadata, client *Client) protocol.Reply {
+	options, _ := c.parseOptions(args)
+	dst := client.DB
+	if options.db >= 0 {
+		dst = options.db
+	}
+	if dst == client.DB && args[1] == args[2] {
+		return protocol.NewError("source and destination objects are the same")
+	}
+	if c.databases.Copy(args[1], args[2], client.DB, dst, options.replace, client.IsMaster) {
+		// Clients of another database may be blocked on the copy
+		client.signalKeyAsReady(dst, args[2])
+		return protocol.Integer(1)
+	}
+	return protocol.Integer(0)
+}
+
+// RandomKeyCommand implements the RANDOMKEY command
+type RandomKeyCommand struct{}
+
+// Execute implements Command.
+func (c *RandomKeyCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	key, found := cache.RandomKey()
+	if !found {
+		return protocol.NullBulkString{}
+	}
+	return protocol.BulkString(key)
+}
+
+// Validate implements Command.
+func (c *RandomKeyCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// KeysCommand implements the KEYS command
+type KeysCommand struct{}
+
+// Execute implements Command.
+func (c *KeysCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return protocol.NewStringArray(cache.Keys(args[1]))
+}
+
+// Validate implements Command.
+func (c *KeysCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
@@ -1,9 +1,6 @@
 package commands
 
 import (
-	"fmt"
-	"strconv"
-
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/storage"
 )
@@ -11,24 +8,22 @@ import (
 type LLenCommand struct{}
 
 // Execute implements Command.
-func (l *LLenCommand) Execute(args []string, cache storage.Cache) string {
+func (l *LLenCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
 	key := args[1]
 
 	redisValue, ok := cache.Get(key)
 	if !ok {
-		return protocol.BuildInteger("0")
+		return protocol.Integer(0)
 	}
 	listValue, ok := redisValue.(*storage.ListValue)
 	if !ok {
-		return protocol.BuildError("wrongtype for listValue")
+		return protocol.ErrWrongType
 	}
-	return protocol.BuildInteger(strconv.Itoa(listValue.Size()))
+	return protocol.Integer(listValue.Size())
 }
 
 // Validate implements Command.
 func (l *LLenCommand) Validate(args []string) error {
-	if len(args) != 2 {
-		return fmt.Errorf("wrong number of arguments to LLEN command")
-	}
+	// Arity is checked by the registry
 	return nil
 }
@@ -10,44 +10,59 @@ import (
 
 type LPopCommand struct{}
 
-func (l *LPopCommand) PopItems(num int, listValue *storage.ListValue) []any {
-	result := make([]any, num)
-	for i := range num {
-		result[i] = listValue.Lpop().Value
+func (l *LPopCommand) PopItems(num int, listValue *storage.ListValue) []string {
+	result := make([]string, 0, num)
+	for range num {
+		item := listValue.Lpop()
+		if item == nil {
+			break
+		}
+		result = append(result, item.Value)
 	}
 	return result
 }
 
 // Execute implements Command.
-func (l *LPopCommand) Execute(args []string, cache storage.Cache) string {
+func (l *LPopCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
 	key := args[1]
 
 	redisValue, ok := cache.Get(key)
 	if !ok {
-		return protocol.BuildBulkString("")
+		return protocol.NullBulkString{}
 	}
 	listValue, ok := redisValue.(*storage.ListValue)
 	if !ok {
-		return protocol.BuildError("wrong type for LPOP")
+		return protocol.ErrWrongType
 	}
 
-	if len(args) == 2 {
-		item := l.PopItems(1, listValue)[0].(string)
-		return protocol.BuildBulkString(item)
-	}
-	num, err := strconv.Atoi(args[2])
-	if err != nil {
-		return protocol.BuildError(err.Error())
+	num := 1
+	if len(args) == 3 {
+		var err error
+		num, err = strconv.Atoi(args[2])
+		if err != nil || num < 0 {
+			return protocol.NewError("value is out of range, must be positive")
+		}
 	}
 	items := l.PopItems(num, listValue)
 
-	return protocol.BuildArray(items)
+	// Like Redis, a list that has been emptied stops existing
+	if listValue.Size() == 0 {
+		cache.Delete(key)
+	}
+
+	if len(args) == 2 {
+		if len(items) == 0 {
+			return protocol.NullBulkString{}
+		}
+		return protocol.BulkString(items[0])
+	}
+	return protocol.NewStringArray(items)
 }
 
 // Validate implements Command.
 func (l *LPopCommand) Validate(args []string) error {
 	if len(args) > 3 {
-		return fmt.Errorf("invalid number of argument to LPOP")
+		return fmt.Errorf("syntax error")
 	}
 
 	return nil
@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"fmt"
 	"strconv"
 
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
@@ -20,43 +19,40 @@ func (l *LRangeCommand) ConvertListItemToAny(items []storage.ListItem) []any {
 }
 
 // Execute implements Command.
-func (l *LRangeCommand) Execute(args []string, cache storage.Cache) string {
+func (l *LRangeCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
 	key := args[1]
 	start, err := strconv.Atoi(args[2])
 	if err != nil {
-		return protocol.BuildError("invalid integer argument")
+		return protocol.NewError("invalid integer argument")
 	}
 	end, err := strconv.Atoi(args[3])
 	if err != nil {
-		return protocol.BuildError("invalid integer argument")
+		return protocol.NewError("invalid integer argument")
 	}
 
 	redisValue, ok := cache.Get(key)
 	if !ok {
-		return protocol.BuildEmptyArray()
+		return protocol.Array{}
 	}
 
 	listValue, ok := redisValue.(*storage.ListValue)
 	if !ok {
-		return protocol.BuildError("key's value is not of List Type")
+		return protocol.ErrWrongType
 	}
 
 	listItems := listValue.GetRangeInclusive(start, end)
 
 	if len(listItems) == 0 {
-		return protocol.BuildEmptyArray()
+		return protocol.Array{}
 	}
 
 	anyItems := l.ConvertListItemToAny(listItems)
 
-	return protocol.BuildArray(anyItems)
+	return protocol.NewArray(anyItems)
 }
 
 // Validate implements Command.
 func (l *LRangeCommand) Validate(args []string) error {
-	if len(args) != 4 {
-		return fmt.Errorf("invalid command arguments for LRANGE")
-	}
-
+	// Arity is checked by the registry
 	return nil
 }
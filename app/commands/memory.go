@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+	"github.com/codecrafters-io/redis-starter-go/app/types"
+	"github.com/codecrafters-io/redis-starter-go/app/utility"
+)
+
+// DefaultMemorySamples is how many elements MEMORY USAGE measures in
+// aggregate values unless told otherwise
+const DefaultMemorySamples = 5
+
+// MemoryCommand implements MEMORY USAGE, MEMORY STATS and MEMORY DOCTOR
+type MemoryCommand struct {
+	databases *storage.Databases
+}
+
+// Execute implements Command.
+func (c *MemoryCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return errExecuteUnreachable
+}
+
+// Validate implements Command.
+func (c *MemoryCommand) Validate(args []string) error {
+	switch strings.ToUpper(args[1]) {
+	case "USAGE":
+		if len(args) != 3 && len(args) != 5 {
+			return errors.New("wrong number of arguments for 'memory|usage' command")
+		}
+		_, err := parseMemorySamples(args)
+		return err
+	case "STATS", "DOCTOR":
+		if len(args) != 2 {
+			return fmt.Errorf("wrong number of arguments for 'memory|%s' command", strings.ToLower(args[1]))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown subcommand '%s'. Try MEMORY HELP.", args[1])
+}
+
+func parseMemorySamples(args []string) (int, error) {
+	if len(args) < 5 {
+		return DefaultMemorySamples, nil
+	}
+	if strings.ToUpper(args[3]) != "SAMPLES" {
+		return 0, errors.New(protocol.SYNTAX_ERROR)
+	}
+	samples, err := strconv.Atoi(args[4])
+	if err != nil || samples < 0 {
+		return 0, errors.New(protocol.NOT_AN_INTEGER)
+	}
+	return samples, nil
+}
+
+func (c *MemoryCommand) ExecuteWithMetadata(args []string, cache storage.Cache, metadata *types.ServerMetadata) protocol.Reply {
+	switch strings.ToUpper(args[1]) {
+	case "USAGE":
+		// Measuring a key doesn't count as an access, it would change which
+		// keys get evicted
+		samples, _ := parseMemorySamples(args)
+		size, exists := cache.MemoryUsage(args[2], samples)
+		if !exists {
+			return protocol.NullBulkString{}
+		}
+		return protocol.Integer(size)
+	case "STATS":
+		return c.stats()
+	default:
+		return protocol.Verbatim{Format: "txt", Text: c.doctor(metadata)}
+	}
+}
+
+func (c *MemoryCommand) stats() protocol.Reply {
+	var total storage.MemoryStats
+	perDB := make(protocol.Map, 0)
+	for index, db := range c.databases.MemoryStats() {
+		total.Keys += db.Keys
+		total.VolatileKeys += db.VolatileKeys
+		total.Used += db.Used
+		total.MainOverhead += db.MainOverhead
+		total.ExpiresOverhead += db.ExpiresOverhead
+		if db.Keys == 0 {
+			continue
+		}
+		perDB = append(perDB, protocol.MapEntry{
+			Key: protocol.BulkString("db." + strconv.Itoa(index)),
+			Value: protocol.Map{
+				{Key: protocol.BulkString("overhead.hashtable.main"), Value: protocol.Integer(db.MainOverhead)},
+				{Key: protocol.BulkString("overhead.hashtable.expires"), Value: protocol.Integer(db.ExpiresOverhead)},
+			},
+		})
+	}
+
+	bytesPerKey := int64(0)
+	datasetPerc := 0.0
+	if total.Keys > 0 {
+		bytesPerKey = total.Used / int64(total.Keys)
+	}
+	if total.Used > 0 {
+		datasetPerc = float64(total.Dataset()) * 100 / float64(total.Used)
+	}
+
+	reply := protocol.Map{
+		{Key: protocol.BulkString("total.allocated"), Value: protocol.Integer(total.Used)},
+		{Key: protocol.BulkString("overhead.total"), Value: protocol.Integer(total.MainOverhead + total.ExpiresOverhead)},
+		{Key: protocol.BulkString("keys.count"), Value: protocol.Integer(total.Keys)},
+		{Key: protocol.BulkString("keys.bytes-per-key"), Value: protocol.Integer(bytesPerKey)},
+		{Key: protocol.BulkString("dataset.bytes"), Value: protocol.Integer(total.Dataset())},
+		{Key: protocol.BulkString("dataset.percentage"), Value: protocol.Double(datasetPerc)},
+	}
+	return append(reply, perDB...)
+}
+
+// doctor reports the memory issues it can spot in plain English
+func (c *MemoryCommand) doctor(metadata *types.ServerMetadata) string {
+	used := c.databases.UsedMemory()
+	if used == 0 {
+		return "Hi Sam, this instance is empty or is using very little memory, my issues detector can't be used in these conditions. " +
+			"Please, leave for your mission on Earth and fill it with some data. The new Sam and I will be back to our programming as soon as I finished rebooting."
+	}
+
+	issues := make([]string, 0)
+	if metadata.MaxMemory > 0 && used*10 >= metadata.MaxMemory*9 {
+		if metadata.MaxMemoryPolicy == string(storage.NoEviction) {
+			issues = append(issues, fmt.Sprintf(" * High memory usage: %s of the %s maxmemory is used and the noeviction policy will make writes fail with OOM errors once it is reached. "+
+				"Consider raising maxmemory or picking an eviction policy.",
+				utility.BytesToHuman(used), utility.BytesToHuman(metadata.MaxMemory)))
+		} else if c.databases.EvictedKeys() > 0 {
+			issues = append(issues, fmt.Sprintf(" * Evictions: %d keys were evicted to stay under maxmemory, the dataset doesn't fit in %s.",
+				c.databases.EvictedKeys(), utility.BytesToHuman(metadata.MaxMemory)))
+		}
+	}
+	if stale := c.databases.ExpireStats().StalePerc; stale > storage.ActiveExpireCycleAcceptableStale*2 {
+		issues = append(issues, fmt.Sprintf(" * Expired keys: about %.2f%% of the keys with a TTL expired but still use memory, the active expiration can't keep up.", stale))
+	}
+
+	if len(issues) == 0 {
+		return "Hi Sam, I can't find any memory issue in your instance. I can only account for what occurs on this base."
+	}
+	return "Sam, I detected a few issues in this Redis instance memory implants:\n\n" +
+		strings.Join(issues, "\n\n") +
+		"\n\nI'm here to keep you safe, Sam. I want to help you."
+}
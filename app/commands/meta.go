@@ -1,8 +1,6 @@
 package commands
 
 import (
-	"errors"
-
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/storage"
 )
@@ -10,15 +8,13 @@ import (
 // TypeCommand implements the TYPE command
 type TypeCommand struct{}
 
-func (c *TypeCommand) Execute(args []string, cache storage.Cache) string {
+func (c *TypeCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
 	key := args[1]
 	dataType := cache.Type(key)
-	return protocol.BuildSimpleString(dataType)
+	return protocol.SimpleString(dataType)
 }
 
 func (c *TypeCommand) Validate(args []string) error {
-	if len(args) < 2 {
-		return errors.New("wrong number of arguments for 'type' command")
-	}
+	// Arity is checked by the registry
 	return nil
 }
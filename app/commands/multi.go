@@ -1,8 +1,6 @@
 package commands
 
 import (
-	"fmt"
-
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/storage"
 )
@@ -12,29 +10,36 @@ type MultiCommand struct{}
 type ExecCommand struct{}
 
 // Execute implements Command.
-func (e *ExecCommand) Execute(args []string, cache storage.Cache) string {
-	return protocol.BuildError(protocol.EXEC_BEFORE_MULTI)
+func (e *ExecCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return protocol.NewError(protocol.EXEC_BEFORE_MULTI)
 }
 
 // Validate implements Command.
 func (e *ExecCommand) Validate(args []string) error {
-	if len(args) != 1 {
-		return fmt.Errorf("invalid arguments")
-	}
-
+	// Arity is checked by the registry
 	return nil
 }
 
 // Execute implements Command.
-func (m *MultiCommand) Execute(args []string, cache storage.Cache) string {
-	return protocol.BuildBulkString("OK")
+func (m *MultiCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return protocol.OK
 }
 
 // Validate implements Command.
 func (m *MultiCommand) Validate(args []string) error {
-	if len(args) != 1 {
-		return fmt.Errorf("invalid arguments")
-	}
+	// Arity is checked by the registry
+	return nil
+}
+
+type DiscardCommand struct{}
 
+// Execute implements Command.
+func (d *DiscardCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return protocol.NewError(protocol.DISCARD_WITHOUT_MULTI)
+}
+
+// Validate implements Command.
+func (d *DiscardCommand) Validate(args []string) error {
+	// Arity is checked by the registry
 	return nil
 }
@@ -13,8 +13,8 @@ type PSyncCommand struct {
 }
 
 // Execute implements Command.
-func (p *PSyncCommand) Execute(args []string, cache storage.Cache) string {
-	return "Accidently call execute of server aware command"
+func (p *PSyncCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return protocol.NewError("Accidently call execute of server aware command")
 }
 
 // Validate implements Command.
@@ -25,13 +25,13 @@ func (p *PSyncCommand) Validate(args []string) error {
 	return nil
 }
 
-func (p *PSyncCommand) ExecuteWithMetadata(args []string, cache storage.Cache, metadata *types.ServerMetadata) []string {
+func (p *PSyncCommand) ExecuteWithMetadata(args []string, cache storage.Cache, metadata *types.ServerMetadata) protocol.Reply {
 	replicationId := metadata.MasterReplID
 	offset := metadata.MasterReplOffset
-	masterResponse := protocol.BuildSimpleString(fmt.Sprintf("FULLRESYNC %s %d", replicationId, offset))
+	masterResponse := protocol.SimpleString(fmt.Sprintf("FULLRESYNC %s %d", replicationId, offset))
 
 	// Only return FULLRESYNC - RDB will be handled separately
-	return []string{masterResponse}
+	return masterResponse
 }
 
 // Add method to get RDB data
@@ -1,26 +1,123 @@
 package commands
 
 import (
+	"time"
+
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/storage"
 	"github.com/codecrafters-io/redis-starter-go/app/types"
 )
 
+// Propagator is implemented by write commands that must reach replicas as
+// something other than what the client sent, e.g. BLPOP is replicated as the
+// LPOP it ended up performing
+type Propagator interface {
+	Propagate(args []string, reply protocol.Reply) [][]string
+}
+
+// TimedCommand is implemented by write commands that turn relative times into
+// absolute ones, e.g. SET ... EX. Like the command time snapshot of Redis,
+// QueueCommand hands them the same instant when executing and propagating, so
+// replicas get exactly the deadline the master stored.
+type TimedCommand interface {
+	Command
+	ExecuteAt(args []string, cache storage.Cache, now time.Time) protocol.Reply
+	PropagateAt(args []string, reply protocol.Reply, now time.Time) [][]string
+}
+
 type QueueCommand struct {
 	Cmd       Command
+	Info      *CommandInfo
 	Args      []string
 	Timestamp int64
 	Metadata  *types.ServerMetadata
+	Client    *Client
+	// now is when the command executed, see TimedCommand
+	now time.Time
+	// propagated is set when the write that served the blocked command
+	// already sent its effect to replicas, see BlockedClients.ServeReady
+	propagated bool
 }
 
-func (q *QueueCommand) Execute(cache storage.Cache) []string {
+func (q *QueueCommand) Execute(cache storage.Cache) protocol.Reply {
+	if q.Info != nil {
+		if err := q.Info.CheckArity(q.Args); err != nil {
+			return protocol.ErrorFromErr(err)
+		}
+	}
+
 	if err := q.Cmd.Validate(q.Args); err != nil {
-		return []string{protocol.BuildError(err.Error())}
+		return protocol.ErrorFromErr(err)
+	}
+
+	if q.Client != nil && q.Client.IsMaster {
+		cache = storage.IgnoringExpiry(cache)
+	}
+	q.now = time.Now()
+	if q.Client != nil {
+		q.Client.running = q
+	}
+	reply := q.execute(cache)
+	if q.Client != nil {
+		q.Client.running = nil
+	}
+
+	// Writes may have grown or shrunk their keys in place, and clients may be
+	// blocked waiting for them
+	if q.Info != nil && q.Info.Has(FlagWrite) {
+		keys := make([]string, 0)
+		for _, pos := range q.keyPositions() {
+			cache.RefreshSize(q.Args[pos])
+			keys = append(keys, q.Args[pos])
+		}
+		if _, failed := reply.(protocol.Error); !failed && q.Client != nil {
+			q.Client.signalKeysAsReady(keys)
+		}
+	}
+	return reply
+}
+
+// keyPositions returns the indexes of the key arguments, asking the command
+// itself when its keys move around
+func (q *QueueCommand) keyPositions() []int {
+	if movable, ok := q.Cmd.(MovableKeysCommand); ok {
+		return movable.KeyPositions(q.Args)
+	}
+	return q.Info.KeyPositions(q.Args)
+}
+
+func (q *QueueCommand) execute(cache storage.Cache) protocol.Reply {
+	if timedCmd, ok := q.Cmd.(TimedCommand); ok {
+		return timedCmd.ExecuteAt(q.Args, cache, q.now)
+	}
+
+	if clientCmd, ok := q.Cmd.(ClientAwareCommand); ok {
+		return clientCmd.ExecuteWithClient(q.Args, cache, q.Metadata, q.Client)
 	}
 
 	if serverCmd, ok := q.Cmd.(ServerAwareCommand); ok {
 		return serverCmd.ExecuteWithMetadata(q.Args, cache, q.Metadata)
 	}
 
-	return []string{q.Cmd.Execute(q.Args, cache)}
+	return q.Cmd.Execute(q.Args, cache)
+}
+
+// Propagation returns the commands replicas need to apply to reproduce the
+// effect of this command once it produced reply. Reads and failed writes
+// don't propagate anything, nor do blocked commands a write already
+// propagated when serving them.
+func (q *QueueCommand) Propagation(reply protocol.Reply) [][]string {
+	if q.Info == nil || !q.Info.Has(FlagWrite) || q.propagated {
+		return nil
+	}
+	if _, failed := reply.(protocol.Error); failed {
+		return nil
+	}
+	if timedCmd, ok := q.Cmd.(TimedCommand); ok {
+		return timedCmd.PropagateAt(q.Args, reply, q.now)
+	}
+	if propagator, ok := q.Cmd.(Propagator); ok {
+		return propagator.Propagate(q.Args, reply)
+	}
+	return [][]string{q.Args}
 }
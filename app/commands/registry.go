@@ -2,35 +2,65 @@ package commands
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/storage"
 	"github.com/codecrafters-io/redis-starter-go/app/types"
 )
 
 // Command interface defines the contract for Redis commands
 type Command interface {
-	Execute(args []string, cache storage.Cache) string
+	Execute(args []string, cache storage.Cache) protocol.Reply
 	Validate(args []string) error
 }
 
 type ServerAwareCommand interface {
 	Command
-	ExecuteWithMetadata(args []string, cache storage.Cache, metadata *types.ServerMetadata) []string
+	ExecuteWithMetadata(args []string, cache storage.Cache, metadata *types.ServerMetadata) protocol.Reply
 }
 
+// ClientAwareCommand is implemented by commands whose reply depends on, or
+// which change, the state of the calling connection
+type ClientAwareCommand interface {
+	Command
+	ExecuteWithClient(args []string, cache storage.Cache, metadata *types.ServerMetadata, client *Client) protocol.Reply
+}
+
+// MovableKeysCommand is implemented by commands whose keys can't be located
+// from the key positions they are registered with, e.g. XREAD ... STREAMS
+type MovableKeysCommand interface {
+	Command
+	KeyPositions(args []string) []int
+}
+
+// BlockingCommand is implemented by blocking commands that only block for
+// some of their arguments, e.g. XREAD without BLOCK returns right away
+type BlockingCommand interface {
+	Command
+	Blocks(args []string) bool
+}
+
+// errExecuteUnreachable is what Execute replies for the commands that always
+// run through ExecuteWithClient or ExecuteWithMetadata
+var errExecuteUnreachable = protocol.NewError("This function shouldn't be called")
+
 // CommandRegistry manages all available Redis commands
 type CommandRegistry struct {
 	commands map[string]Command
+	infos    map[string]*CommandInfo
+	blocked  *BlockedClients
 }
 
 type CommandExecutionResult struct {
-	Response string
+	Response protocol.Reply
 	Error    error
 	Success  bool
 }
 
-func NewCommandExecutionResult(response string, err error) *CommandExecutionResult {
+func NewCommandExecutionResult(response protocol.Reply, err error) *CommandExecutionResult {
 	return &CommandExecutionResult{
 		Response: response,
 		Error:    err,
@@ -38,59 +68,230 @@ func NewCommandExecutionResult(response string, err error) *CommandExecutionResu
 	}
 }
 
-// NewCommandRegistry creates a new command registry with all commands
-func NewCommandRegistry() *CommandRegistry {
+// NewCommandRegistry creates a new command registry with all commands, the
+// database commands operate on databases
+func NewCommandRegistry(databases *storage.Databases) *CommandRegistry {
 	registry := &CommandRegistry{
 		commands: make(map[string]Command),
+		infos:    make(map[string]*CommandInfo),
+		blocked:  NewBlockedClients(databases),
 	}
 
 	// Register all commands
-	registry.Register("PING", &PingCommand{})
-	registry.Register("ECHO", &EchoCommand{})
-	registry.Register("GET", &GetCommand{})
-	registry.Register("SET", &SetCommand{})
-	registry.Register("TYPE", &TypeCommand{})
-	registry.Register("XADD", &XAddCommand{})
-	registry.Register("XRANGE", &XRangeCommand{})
-	registry.Register("XREAD", &XReadCommand{})
-	registry.Register("RPUSH", &RPushCommand{})
-	registry.Register("LRANGE", &LRangeCommand{})
-	registry.Register("LPUSH", &LPushCommand{})
-	registry.Register("LLEN", &LLenCommand{})
-	registry.Register("LPOP", &LPopCommand{})
-	registry.Register("BLPOP", &BLPopCommand{})
-	registry.Register("INCR", &IncrCommand{})
-	registry.Register("MULTI", &MultiCommand{})
-	registry.Register("EXEC", &ExecCommand{})
-	registry.Register("INFO", &InfoCommand{})
-	registry.Register("REPLCONF", &ReplConfCommand{})
-	registry.Register("PSYNC", &PSyncCommand{})
-	registry.Register("WAIT", &WaitCommand{})
-	registry.Register("CONFIG", &ConfigGetCommand{})
+	registry.Register("PING", &PingCommand{}, CommandInfo{Arity: -1, Flags: FlagFast, Summary: "Returns the server's liveliness response.", Group: GroupConnection})
+	registry.Register("ECHO", &EchoCommand{}, CommandInfo{Arity: 2, Flags: FlagFast, Summary: "Returns the given string.", Group: GroupConnection})
+	registry.Register("HELLO", &HelloCommand{}, CommandInfo{Arity: -1, Flags: FlagNoScript | FlagLoadingOK | FlagStaleOK | FlagFast, Summary: "Handshakes with the Redis server.", Group: GroupConnection})
+	registry.Register("CLIENT", &ClientCommand{blocked: registry.blocked}, CommandInfo{Arity: -2, Flags: FlagNoScript | FlagLoadingOK | FlagStaleOK, Summary: "A container for client connection commands.", Group: GroupConnection})
+
+	registry.Register("GET", &GetCommand{}, CommandInfo{Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the string value of a key.", Group: GroupString})
+	registry.Register("SET", &SetCommand{}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.", Group: GroupString})
+	registry.Register("INCR", &IncrCommand{}, CommandInfo{Arity: 2, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Increments the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.", Group: GroupString})
+	registry.Register("TYPE", &TypeCommand{}, CommandInfo{Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Determines the type of value stored at a key.", Group: GroupGeneric})
+
+	registry.Register("KEYS", &KeysCommand{}, CommandInfo{Arity: 2, Flags: FlagReadOnly, Summary: "Returns all key names that match a pattern.", Group: GroupGeneric})
+	registry.Register("SCAN", &ScanCommand{}, CommandInfo{Arity: -2, Flags: FlagReadOnly, Summary: "Iterates over the key names in the database.", Group: GroupGeneric})
+	registry.Register("DEL", &DelCommand{}, CommandInfo{Arity: -2, Flags: FlagWrite, FirstKey: 1, LastKey: -1, Step: 1, Summary: "Deletes one or more keys.", Group: GroupGeneric})
+	registry.Register("UNLINK", &DelCommand{}, CommandInfo{Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: -1, Step: 1, Summary: "Asynchronously deletes one or more keys.", Group: GroupGeneric})
+	registry.Register("EXISTS", &ExistsCommand{}, CommandInfo{Arity: -2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: -1, Step: 1, Summary: "Determines whether one or more keys exist.", Group: GroupGeneric})
+	registry.Register("TOUCH", &TouchCommand{}, CommandInfo{Arity: -2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: -1, Step: 1, Summary: "Returns the number of existing keys out of those specified after updating the time they were last accessed.", Group: GroupGeneric})
+	registry.Register("RENAME", &RenameCommand{}, CommandInfo{Arity: 3, Flags: FlagWrite, FirstKey: 1, LastKey: 2, Step: 1, Summary: "Renames a key and overwrites the destination.", Group: GroupGeneric})
+	registry.Register("RENAMENX", &RenameCommand{nx: true}, CommandInfo{Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 2, Step: 1, Summary: "Renames a key only when the target key name doesn't exist.", Group: GroupGeneric})
+	registry.Register("COPY", &CopyCommand{databases: databases}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 2, Step: 1, Summary: "Copies the value of a key to a new key.", Group: GroupGeneric})
+	registry.Register("RANDOMKEY", &RandomKeyCommand{}, CommandInfo{Arity: 1, Flags: FlagReadOnly, Summary: "Returns a random key name from the database.", Group: GroupGeneric})
+	registry.Register("EXPIRE", &ExpireCommand{name: "expire", unit: time.Second}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Sets the expiration time of a key in seconds.", Group: GroupGeneric})
+	registry.Register("PEXPIRE", &ExpireCommand{name: "pexpire", unit: time.Millisecond}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Sets the expiration time of a key in milliseconds.", Group: GroupGeneric})
+	registry.Register("EXPIREAT", &ExpireCommand{name: "expireat", unit: time.Second, absolute: true}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Sets the expiration time of a key to a Unix timestamp.", Group: GroupGeneric})
+	registry.Register("PEXPIREAT", &ExpireCommand{name: "pexpireat", unit: time.Millisecond, absolute: true}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Sets the expiration time of a key to a Unix milliseconds timestamp.", Group: GroupGeneric})
+	registry.Register("TTL", &TTLCommand{unit: time.Second}, CommandInfo{Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the expiration time in seconds of a key.", Group: GroupGeneric})
+	registry.Register("PTTL", &TTLCommand{unit: time.Millisecond}, CommandInfo{Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the expiration time in milliseconds of a key.", Group: GroupGeneric})
+	registry.Register("EXPIRETIME", &TTLCommand{unit: time.Second, absolute: true}, CommandInfo{Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the expiration time of a key as a Unix timestamp.", Group: GroupGeneric})
+	registry.Register("PEXPIRETIME", &TTLCommand{unit: time.Millisecond, absolute: true}, CommandInfo{Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the expiration time of a key as a Unix milliseconds timestamp.", Group: GroupGeneric})
+	registry.Register("PERSIST", &PersistCommand{}, CommandInfo{Arity: 2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Removes the expiration time of a key.", Group: GroupGeneric})
+
+	registry.Register("XADD", &XAddCommand{}, CommandInfo{Arity: -5, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Appends a new message to a stream. Creates the key if it doesn't exist.", Group: GroupStream})
+	registry.Register("XRANGE", &XRangeCommand{}, CommandInfo{Arity: 4, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the messages from a stream within a range of IDs.", Group: GroupStream})
+	registry.Register("XREAD", &XReadCommand{blocked: registry.blocked}, CommandInfo{Arity: -4, Flags: FlagReadOnly | FlagBlocking | FlagMovableKeys, Summary: "Returns messages from multiple streams with IDs greater than the ones requested. Blocks until a message is available otherwise.", Group: GroupStream})
+
+	registry.Register("RPUSH", &RPushCommand{}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Appends one or more elements to a list. Creates the key if it doesn't exist.", Group: GroupList})
+	registry.Register("LPUSH", &LPushCommand{}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Prepends one or more elements to a list. Creates the key if it doesn't exist.", Group: GroupList})
+	registry.Register("LRANGE", &LRangeCommand{}, CommandInfo{Arity: 4, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns a range of elements from a list.", Group: GroupList})
+	registry.Register("LLEN", &LLenCommand{}, CommandInfo{Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the length of a list.", Group: GroupList})
+	registry.Register("LPOP", &LPopCommand{}, CommandInfo{Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the first elements in a list after removing it. Deletes the list if the last element was popped.", Group: GroupList})
+	registry.Register("BLPOP", &BLPopCommand{blocked: registry.blocked}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagBlocking, FirstKey: 1, LastKey: -2, Step: 1, Summary: "Removes and returns the first element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.", Group: GroupList})
+
+	registry.Register("HSET", &HSetCommand{name: "hset"}, CommandInfo{Arity: -4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Creates or modifies the value of a field in a hash.", Group: GroupHash})
+	registry.Register("HMSET", &HSetCommand{name: "hmset"}, CommandInfo{Arity: -4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Sets the values of multiple fields.", Group: GroupHash})
+	registry.Register("HSETNX", &HSetNXCommand{}, CommandInfo{Arity: 4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Sets the value of a field in a hash only when the field doesn't exist.", Group: GroupHash})
+	registry.Register("HGET", &HGetCommand{}, CommandInfo{Arity: 3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the value of a field in a hash.", Group: GroupHash})
+	registry.Register("HMGET", &HMGetCommand{}, CommandInfo{Arity: -3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the values of all fields in a hash.", Group: GroupHash})
+	registry.Register("HGETALL", &HGetAllCommand{keys: true, values: true}, CommandInfo{Arity: 2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns all fields and values in a hash.", Group: GroupHash})
+	registry.Register("HKEYS", &HGetAllCommand{keys: true}, CommandInfo{Arity: 2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns all fields in a hash.", Group: GroupHash})
+	registry.Register("HVALS", &HGetAllCommand{values: true}, CommandInfo{Arity: 2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns all values in a hash.", Group: GroupHash})
+	registry.Register("HDEL", &HDelCommand{}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Deletes one or more fields and their values from a hash. Deletes the hash if no fields remain.", Group: GroupHash})
+	registry.Register("HEXISTS", &HExistsCommand{}, CommandInfo{Arity: 3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Determines whether a field exists in a hash.", Group: GroupHash})
+	registry.Register("HLEN", &HLenCommand{}, CommandInfo{Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the number of fields in a hash.", Group: GroupHash})
+	registry.Register("HSTRLEN", &HStrLenCommand{}, CommandInfo{Arity: 3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the length of the value of a field.", Group: GroupHash})
+	registry.Register("HINCRBY", &HIncrByCommand{}, CommandInfo{Arity: 4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Increments the integer value of a field in a hash by a number. Uses 0 as initial value if the field doesn't exist.", Group: GroupHash})
+	registry.Register("HINCRBYFLOAT", &HIncrByFloatCommand{}, CommandInfo{Arity: 4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Increments the floating point value of a field by a number. Uses 0 as initial value if the field doesn't exist.", Group: GroupHash})
+	registry.Register("HRANDFIELD", &HRandFieldCommand{}, CommandInfo{Arity: -2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns one or more random fields from a hash.", Group: GroupHash})
+	registry.Register("HSCAN", &HScanCommand{}, CommandInfo{Arity: -3, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Iterates over fields and values of a hash.", Group: GroupHash})
+
+	registry.Register("HEXPIRE", &HExpireCommand{name: "hexpire", unit: time.Second}, CommandInfo{Arity: -6, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Set expiry for hash field using relative time to expire (seconds).", Group: GroupHash})
+	registry.Register("HPEXPIRE", &HExpireCommand{name: "hpexpire", unit: time.Millisecond}, CommandInfo{Arity: -6, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Set expiry for hash field using relative time to expire (milliseconds).", Group: GroupHash})
+	registry.Register("HEXPIREAT", &HExpireCommand{name: "hexpireat", unit: time.Second, absolute: true}, CommandInfo{Arity: -6, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Set expiry for hash field using an absolute Unix timestamp (seconds).", Group: GroupHash})
+	registry.Register("HPEXPIREAT", &HExpireCommand{name: "hpexpireat", unit: time.Millisecond, absolute: true}, CommandInfo{Arity: -6, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Set expiry for hash field using an absolute Unix timestamp (milliseconds).", Group: GroupHash})
+	registry.Register("HTTL", &HTTLCommand{unit: time.Second}, CommandInfo{Arity: -5, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the TTL in seconds of a hash field.", Group: GroupHash})
+	registry.Register("HPTTL", &HTTLCommand{unit: time.Millisecond}, CommandInfo{Arity: -5, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the TTL in milliseconds of a hash field.", Group: GroupHash})
+	registry.Register("HEXPIRETIME", &HTTLCommand{unit: time.Second, absolute: true}, CommandInfo{Arity: -5, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the expiration time of a hash field as a Unix timestamp, in seconds.", Group: GroupHash})
+	registry.Register("HPEXPIRETIME", &HTTLCommand{unit: time.Millisecond, absolute: true}, CommandInfo{Arity: -5, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the expiration time of a hash field as a Unix timestamp, in msec.", Group: GroupHash})
+	registry.Register("HPERSIST", &HPersistCommand{}, CommandInfo{Arity: -5, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Removes the expiration time for each specified field.", Group: GroupHash})
+	registry.Register("HGETEX", &HGetExCommand{}, CommandInfo{Arity: -5, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Get the value of one or more fields of a given hash key, and optionally set their expiration.", Group: GroupHash})
+	registry.Register("HSETEX", &HSetExCommand{}, CommandInfo{Arity: -6, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Set the value of one or more fields of a given hash key, and optionally set their expiration.", Group: GroupHash})
+
+	registry.Register("SADD", &SAddCommand{}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Adds one or more members to a set. Creates the key if it doesn't exist.", Group: GroupSet})
+	registry.Register("SREM", &SRemCommand{}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Removes one or more members from a set. Deletes the set if the last member was removed.", Group: GroupSet})
+	registry.Register("SMEMBERS", &SMembersCommand{}, CommandInfo{Arity: 2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns all members of a set.", Group: GroupSet})
+	registry.Register("SISMEMBER", &SIsMemberCommand{}, CommandInfo{Arity: 3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Determines whether a member belongs to a set.", Group: GroupSet})
+	registry.Register("SMISMEMBER", &SIsMemberCommand{multiple: true}, CommandInfo{Arity: -3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Determines whether multiple members belong to a set.", Group: GroupSet})
+	registry.Register("SCARD", &SCardCommand{}, CommandInfo{Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the number of members in a set.", Group: GroupSet})
+	registry.Register("SPOP", &SPopCommand{}, CommandInfo{Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns one or more random members from a set after removing them. Deletes the set if the last member was popped.", Group: GroupSet})
+	registry.Register("SRANDMEMBER", &SRandMemberCommand{}, CommandInfo{Arity: -2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns one or more random members from a set.", Group: GroupSet})
+	registry.Register("SMOVE", &SMoveCommand{}, CommandInfo{Arity: 4, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 2, Step: 1, Summary: "Moves a member from one set to another.", Group: GroupSet})
+	registry.Register("SINTER", &SetOperationCommand{op: setInter}, CommandInfo{Arity: -2, Flags: FlagReadOnly, FirstKey: 1, LastKey: -1, Step: 1, Summary: "Returns the intersect of multiple sets.", Group: GroupSet})
+	registry.Register("SINTERCARD", &SInterCardCommand{}, CommandInfo{Arity: -3, Flags: FlagReadOnly | FlagMovableKeys, Summary: "Returns the number of members of the intersect of multiple sets.", Group: GroupSet})
+	registry.Register("SINTERSTORE", &SetOperationCommand{op: setInter, store: true}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: -1, Step: 1, Summary: "Stores the intersect of multiple sets in a key.", Group: GroupSet})
+	registry.Register("SUNION", &SetOperationCommand{op: setUnion}, CommandInfo{Arity: -2, Flags: FlagReadOnly, FirstKey: 1, LastKey: -1, Step: 1, Summary: "Returns the union of multiple sets.", Group: GroupSet})
+	registry.Register("SUNIONSTORE", &SetOperationCommand{op: setUnion, store: true}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: -1, Step: 1, Summary: "Stores the union of multiple sets in a key.", Group: GroupSet})
+	registry.Register("SDIFF", &SetOperationCommand{op: setDiff}, CommandInfo{Arity: -2, Flags: FlagReadOnly, FirstKey: 1, LastKey: -1, Step: 1, Summary: "Returns the difference of multiple sets.", Group: GroupSet})
+	registry.Register("SDIFFSTORE", &SetOperationCommand{op: setDiff, store: true}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: -1, Step: 1, Summary: "Stores the difference of multiple sets in a key.", Group: GroupSet})
+	registry.Register("SSCAN", &SScanCommand{}, CommandInfo{Arity: -3, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Iterates over members of a set.", Group: GroupSet})
+
+	registry.Register("ZADD", &ZAddCommand{}, CommandInfo{Arity: -4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Adds one or more members to a sorted set, or updates their scores. Creates the key if it doesn't exist.", Group: GroupSortedSet})
+	registry.Register("ZREM", &ZRemCommand{}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Removes one or more members from a sorted set. Deletes the sorted set if all members were removed.", Group: GroupSortedSet})
+	registry.Register("ZSCORE", &ZScoreCommand{}, CommandInfo{Arity: 3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the score of a member in a sorted set.", Group: GroupSortedSet})
+	registry.Register("ZMSCORE", &ZScoreCommand{multiple: true}, CommandInfo{Arity: -3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the score of one or more members in a sorted set.", Group: GroupSortedSet})
+	registry.Register("ZINCRBY", &ZIncrByCommand{}, CommandInfo{Arity: 4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Increments the score of a member in a sorted set.", Group: GroupSortedSet})
+	registry.Register("ZCARD", &ZCardCommand{}, CommandInfo{Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the number of members in a sorted set.", Group: GroupSortedSet})
+	registry.Register("ZCOUNT", &ZCountCommand{}, CommandInfo{Arity: 4, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the count of members in a sorted set that have scores within a range.", Group: GroupSortedSet})
+	registry.Register("ZRANK", &ZRankCommand{}, CommandInfo{Arity: -3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the index of a member in a sorted set ordered by ascending scores.", Group: GroupSortedSet})
+	registry.Register("ZREVRANK", &ZRankCommand{reverse: true}, CommandInfo{Arity: -3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the index of a member in a sorted set ordered by descending scores.", Group: GroupSortedSet})
+	registry.Register("ZRANGE", &ZRangeCommand{}, CommandInfo{Arity: -4, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns members in a sorted set within a range of indexes.", Group: GroupSortedSet})
+	registry.Register("ZRANGESTORE", &ZRangeStoreCommand{}, CommandInfo{Arity: -5, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 2, Step: 1, Summary: "Stores a range of members from sorted set in a key.", Group: GroupSortedSet})
+	registry.Register("ZPOPMIN", &ZPopCommand{}, CommandInfo{Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the lowest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.", Group: GroupSortedSet})
+	registry.Register("ZPOPMAX", &ZPopCommand{highest: true}, CommandInfo{Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns the highest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.", Group: GroupSortedSet})
+	registry.Register("ZRANDMEMBER", &ZRandMemberCommand{}, CommandInfo{Arity: -2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Returns one or more random members from a sorted set.", Group: GroupSortedSet})
+	registry.Register("ZREMRANGEBYRANK", &ZRemRangeCommand{by: zrangeByRank}, CommandInfo{Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Removes members in a sorted set within a range of indexes. Deletes the sorted set if all members were removed.", Group: GroupSortedSet})
+	registry.Register("ZREMRANGEBYSCORE", &ZRemRangeCommand{by: zrangeByScore}, CommandInfo{Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Removes members in a sorted set within a range of scores. Deletes the sorted set if all members were removed.", Group: GroupSortedSet})
+	registry.Register("ZREMRANGEBYLEX", &ZRemRangeCommand{by: zrangeByLex}, CommandInfo{Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Removes members in a sorted set within a lexicographical range. Deletes the sorted set if all members were removed.", Group: GroupSortedSet})
+	registry.Register("ZUNION", &ZSetOperationCommand{op: setUnion}, CommandInfo{Arity: -3, Flags: FlagReadOnly | FlagMovableKeys, Summary: "Returns the union of multiple sorted sets.", Group: GroupSortedSet})
+	registry.Register("ZUNIONSTORE", &ZSetOperationCommand{op: setUnion, store: true}, CommandInfo{Arity: -4, Flags: FlagWrite | FlagDenyOOM | FlagMovableKeys, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Stores the union of multiple sorted sets in a key.", Group: GroupSortedSet})
+	registry.Register("ZINTER", &ZSetOperationCommand{op: setInter}, CommandInfo{Arity: -3, Flags: FlagReadOnly | FlagMovableKeys, Summary: "Returns the intersect of multiple sorted sets.", Group: GroupSortedSet})
+	registry.Register("ZINTERSTORE", &ZSetOperationCommand{op: setInter, store: true}, CommandInfo{Arity: -4, Flags: FlagWrite | FlagDenyOOM | FlagMovableKeys, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Stores the intersect of multiple sorted sets in a key.", Group: GroupSortedSet})
+	registry.Register("ZINTERCARD", &ZInterCardCommand{}, CommandInfo{Arity: -3, Flags: FlagReadOnly | FlagMovableKeys, Summary: "Returns the number of members of the intersect of multiple sorted sets.", Group: GroupSortedSet})
+	registry.Register("ZDIFF", &ZSetOperationCommand{op: setDiff}, CommandInfo{Arity: -3, Flags: FlagReadOnly | FlagMovableKeys, Summary: "Returns the difference between multiple sorted sets.", Group: GroupSortedSet})
+	registry.Register("ZDIFFSTORE", &ZSetOperationCommand{op: setDiff, store: true}, CommandInfo{Arity: -4, Flags: FlagWrite | FlagDenyOOM | FlagMovableKeys, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Stores the difference of multiple sorted sets in a key.", Group: GroupSortedSet})
+	registry.Register("ZMPOP", &ZMPopCommand{}, CommandInfo{Arity: -4, Flags: FlagWrite | FlagMovableKeys, Summary: "Returns the highest- or lowest-scoring members from one or more sorted sets after removing them. Deletes the sorted set if the last member was popped.", Group: GroupSortedSet})
+	registry.Register("BZPOPMIN", &BZPopCommand{blocked: registry.blocked}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagBlocking | FlagFast, FirstKey: 1, LastKey: -2, Step: 1, Summary: "Removes and returns the member with the lowest score from one or more sorted sets. Blocks until a member is available otherwise. Deletes the sorted set if the last element was popped.", Group: GroupSortedSet})
+	registry.Register("BZPOPMAX", &BZPopCommand{highest: true, blocked: registry.blocked}, CommandInfo{Arity: -3, Flags: FlagWrite | FlagBlocking | FlagFast, FirstKey: 1, LastKey: -2, Step: 1, Summary: "Removes and returns the member with the highest score from one or more sorted sets. Blocks until a member is available otherwise. Deletes the sorted set if the last element was popped.", Group: GroupSortedSet})
+	registry.Register("BZMPOP", &ZMPopCommand{blocking: true, blocked: registry.blocked}, CommandInfo{Arity: -5, Flags: FlagWrite | FlagBlocking | FlagMovableKeys, Summary: "Removes and returns a member by score from one or more sorted sets. Blocks until a member is available otherwise. Deletes the sorted set if the last element was popped.", Group: GroupSortedSet})
+	registry.Register("ZSCAN", &ZScanCommand{}, CommandInfo{Arity: -3, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Iterates over members and scores of a sorted set.", Group: GroupSortedSet})
+
+	registry.Register("SELECT", &SelectCommand{databases: databases}, CommandInfo{Arity: 2, Flags: FlagLoadingOK | FlagStaleOK | FlagFast, Summary: "Changes the selected database.", Group: GroupConnection})
+	registry.Register("MOVE", &MoveCommand{databases: databases}, CommandInfo{Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, Step: 1, Summary: "Moves a key to another database.", Group: GroupGeneric})
+	registry.Register("SWAPDB", &SwapDBCommand{databases: databases, blocked: registry.blocked}, CommandInfo{Arity: 3, Flags: FlagWrite | FlagFast, Summary: "Swaps two Redis databases.", Group: GroupServer})
+	registry.Register("DBSIZE", &DBSizeCommand{}, CommandInfo{Arity: 1, Flags: FlagReadOnly | FlagFast, Summary: "Returns the number of keys in the database.", Group: GroupServer})
+	registry.Register("FLUSHDB", &FlushDBCommand{}, CommandInfo{Arity: -1, Flags: FlagWrite, Summary: "Removes all keys from the current database.", Group: GroupServer})
+	registry.Register("FLUSHALL", &FlushAllCommand{databases: databases}, CommandInfo{Arity: -1, Flags: FlagWrite, Summary: "Removes all keys from all databases.", Group: GroupServer})
+
+	registry.Register("MULTI", &MultiCommand{}, CommandInfo{Arity: 1, Flags: FlagNoScript | FlagLoadingOK | FlagStaleOK | FlagFast, Summary: "Starts a transaction.", Group: GroupTransactions})
+	registry.Register("EXEC", &ExecCommand{}, CommandInfo{Arity: 1, Flags: FlagNoScript | FlagLoadingOK | FlagStaleOK, Summary: "Executes all commands in a transaction.", Group: GroupTransactions})
+	registry.Register("DISCARD", &DiscardCommand{}, CommandInfo{Arity: 1, Flags: FlagNoScript | FlagLoadingOK | FlagStaleOK | FlagFast, Summary: "Discards a transaction.", Group: GroupTransactions})
+
+	registry.Register("INFO", &InfoCommand{databases: databases}, CommandInfo{Arity: -1, Flags: FlagLoadingOK | FlagStaleOK, Summary: "Returns information and statistics about the server.", Group: GroupServer})
+	registry.Register("CONFIG", &ConfigGetCommand{}, CommandInfo{Arity: -2, Flags: FlagAdmin | FlagNoScript | FlagLoadingOK | FlagStaleOK, Summary: "Returns the effective values of configuration parameters.", Group: GroupServer})
+	registry.Register("MEMORY", &MemoryCommand{databases: databases}, CommandInfo{Arity: -2, Flags: FlagReadOnly, FirstKey: 2, LastKey: 2, Step: 1, Summary: "Reports memory usage details.", Group: GroupServer})
+	registry.Register("REPLCONF", &ReplConfCommand{}, CommandInfo{Arity: -1, Flags: FlagAdmin | FlagNoScript | FlagLoadingOK | FlagStaleOK, Summary: "An internal command for configuring the replication stream.", Group: GroupServer})
+	registry.Register("PSYNC", &PSyncCommand{}, CommandInfo{Arity: -3, Flags: FlagAdmin | FlagNoScript, Summary: "An internal command used in replication.", Group: GroupServer})
+	registry.Register("WAIT", &WaitCommand{}, CommandInfo{Arity: 3, Flags: FlagBlocking, Summary: "Blocks until the asynchronous replication of all preceding write commands sent by the connection is completed.", Group: GroupGeneric})
+	registry.Register("COMMAND", &CommandCommand{registry: registry}, CommandInfo{Arity: -1, Flags: FlagLoadingOK | FlagStaleOK, Summary: "Returns detailed information about all commands.", Group: GroupServer})
 
 	return registry
 }
 
-// Register adds a command to the registry
-func (r *CommandRegistry) Register(name string, cmd Command) {
+// Register adds a command to the registry along with its metadata
+func (r *CommandRegistry) Register(name string, cmd Command, info CommandInfo) {
+	info.Name = strings.ToLower(name)
 	r.commands[strings.ToUpper(name)] = cmd
+	r.infos[strings.ToUpper(name)] = &info
+}
+
+// GetCommandInfo returns the metadata a command was registered with
+func (r *CommandRegistry) GetCommandInfo(cmdName string) (*CommandInfo, bool) {
+	info, exists := r.infos[strings.ToUpper(cmdName)]
+	return info, exists
 }
 
 // // Execute runs a command with the given arguments
 // func (r *CommandRegistry) Execute(cmdName string, args []string, cache storage.Cache) string {
 // 	cmd, exists := r.commands[strings.ToUpper(cmdName)]
 // 	if !exists {
-// 		return protocol.BuildError("unknown command '" + cmdName + "'")
+// 		return protocol.NewError("unknown command '" + cmdName + "'")
 // 	}
 
 // 	if err := cmd.Validate(args); err != nil {
-// 		return protocol.BuildError(err.Error())
+// 		return protocol.NewError(err.Error())
 // 	}
 
 // 	log.Printf("Values of cmd, and args are %s, %v", cmdName, args)
 // 	return cmd.Execute(args, cache)
 // }
 
+// IsBlocking reports whether executing the request may park the connection
+// until another client acts, e.g. BLPOP or XREAD BLOCK
+func (r *CommandRegistry) IsBlocking(args []string) bool {
+	info, exists := r.GetCommandInfo(args[0])
+	if !exists || !info.Has(FlagBlocking) {
+		return false
+	}
+	if blocking, ok := r.commands[strings.ToUpper(args[0])].(BlockingCommand); ok {
+		return blocking.Blocks(args)
+	}
+	return true
+}
+
+// BlockedClients returns the clients blocked on keys, connections serve
+// them after each command with BlockedClients.ServeReady
+func (r *CommandRegistry) BlockedClients() *BlockedClients {
+	return r.blocked
+}
+
+// GetKeys extracts the key arguments of a request
+func (r *CommandRegistry) GetKeys(args []string) []string {
+	info, exists := r.GetCommandInfo(args[0])
+	if !exists {
+		return nil
+	}
+	positions := info.KeyPositions(args)
+	if movable, ok := r.commands[strings.ToUpper(args[0])].(MovableKeysCommand); ok {
+		positions = movable.KeyPositions(args)
+	}
+	keys := make([]string, 0, len(positions))
+	for _, pos := range positions {
+		keys = append(keys, args[pos])
+	}
+	return keys
+}
+
+// CommandInfos returns the metadata of every registered command, sorted by name
+func (r *CommandRegistry) CommandInfos() []*CommandInfo {
+	infos := make([]*CommandInfo, 0, len(r.infos))
+	for _, info := range r.infos {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
 // HasCommand checks if a command exists in the registry
 func (r *CommandRegistry) HasCommand(cmdName string) bool {
 	_, exists := r.commands[strings.ToUpper(cmdName)]
@@ -100,7 +301,7 @@ func (r *CommandRegistry) HasCommand(cmdName string) bool {
 func (r *CommandRegistry) GetCommand(cmdName string) (Command, error) {
 	Cmd, exists := r.commands[strings.ToUpper(cmdName)]
 	if !exists {
-		return nil, fmt.Errorf("Given command: %s is not supported yet", cmdName)
+		return nil, fmt.Errorf("unknown command '%s'", cmdName)
 	}
 	return Cmd, nil
 }
@@ -9,32 +9,29 @@ import (
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/storage"
 	"github.com/codecrafters-io/redis-starter-go/app/types"
-	"github.com/codecrafters-io/redis-starter-go/app/utility"
 )
 
 type ReplConfCommand struct{}
 
 // Execute implements Command.
-func (r *ReplConfCommand) Execute(args []string, cache storage.Cache) string {
-	return "This REPLCONF method shouldn't be called"
+func (r *ReplConfCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return protocol.NewError("This REPLCONF method shouldn't be called")
 }
 
 // Validate implements Command.
 func (r *ReplConfCommand) Validate(args []string) error {
-	if len(args) != 3 {
-		return fmt.Errorf("wrong number of arguments to 'replconf' command")
+	// Options always come as name/value pairs
+	if len(args) < 3 || (len(args)-1)%2 != 0 {
+		return fmt.Errorf("syntax error")
 	}
 	return nil
 }
 
-func (r *ReplConfCommand) ExecuteWithMetadata(args []string, cache storage.Cache, metadata *types.ServerMetadata) []string {
+func (r *ReplConfCommand) ExecuteWithMetadata(args []string, cache storage.Cache, metadata *types.ServerMetadata) protocol.Reply {
 	log.Println("Inside the ExecuteWithMetadata of REPLCONF command")
 
-	resp := make([]string, 0)
 	if strings.ToUpper(args[1]) == "GETACK" {
-		response := utility.ConvertStringArrayToAny([]string{"REPLCONF", "ACK", fmt.Sprintf("%d", metadata.CommandProcessed)})
-		resp = append(resp, protocol.BuildArray(response))
-		return resp
+		return protocol.NewStringArray([]string{"REPLCONF", "ACK", fmt.Sprintf("%d", metadata.CommandProcessed)})
 	} else if strings.ToUpper(args[1]) == "ACK" {
 		// This is an ACK response from a replica - process it for WAIT commands
 		offsetStr := args[2]
@@ -45,9 +42,7 @@ func (r *ReplConfCommand) ExecuteWithMetadata(args []string, cache storage.Cache
 			log.Printf("Received ACK with offset: %d", offset)
 		}
 		// Don't return a response for ACK (replicas don't expect a response to their ACK)
-		return []string{}
+		return nil
 	}
-	resp = append(resp, protocol.BuildSimpleString(protocol.RESPONSE_OK))
-	log.Printf("REPLCONF returning response: %v", resp)
-	return resp
+	return protocol.OK
 }
@@ -1,9 +1,6 @@
 package commands
 
 import (
-	"fmt"
-	"strconv"
-
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/storage"
 )
@@ -17,7 +14,7 @@ func (r *RPushCommand) AppendToList(l *storage.ListValue, argsValues []string) {
 }
 
 // Execute implements Command.
-func (r *RPushCommand) Execute(args []string, cache storage.Cache) string {
+func (r *RPushCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
 	var listValue *storage.ListValue
 	var ok bool
 	key := args[1]
@@ -25,21 +22,20 @@ func (r *RPushCommand) Execute(args []string, cache storage.Cache) string {
 	redisValue, exists := cache.Get(key)
 	if !exists {
 		listValue = storage.NewListValue()
+		// Existing lists are updated in place so they keep their expiration
+		cache.Set(key, listValue)
 	} else {
 		listValue, ok = redisValue.(*storage.ListValue)
 		if !ok {
-			return protocol.BuildError("WRONGTYPE Operation against a key holding the wrong kind of value")
+			return protocol.ErrWrongType
 		}
 	}
 	r.AppendToList(listValue, argsValues)
-	cache.Set(key, listValue)
-	return protocol.BuildInteger(strconv.Itoa(listValue.Size()))
+	return protocol.Integer(listValue.Size())
 }
 
 // Validate implements Command.
 func (r *RPushCommand) Validate(args []string) error {
-	if len(args) < 3 {
-		return fmt.Errorf("invalid number of argument for RPUSH")
-	}
+	// Arity is checked by the registry
 	return nil
 }
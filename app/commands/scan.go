@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/glob"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+)
+
+// DefaultScanCount is how many elements SCAN and its variants return per
+// call unless told otherwise
+const DefaultScanCount = 10
+
+// Options of the SCAN family that only some of the commands accept
+const (
+	scanAllowType = 1 << iota
+	scanAllowNoValues
+)
+
+// scanOptions holds the options shared by SCAN and its per collection
+// variants
+type scanOptions struct {
+	cursor   uint64
+	match    string
+	count    int
+	valType  string
+	noValues bool
+}
+
+// parseScanOptions parses "cursor [MATCH pattern] [COUNT n]" starting at
+// args[0], allowed tells which of TYPE and NOVALUES are accepted as well
+func parseScanOptions(args []string, allowed int) (scanOptions, error) {
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return scanOptions{}, errors.New("invalid cursor")
+	}
+	options := scanOptions{cursor: cursor, count: DefaultScanCount}
+	for i := 1; i < len(args); i += 2 {
+		if allowed&scanAllowNoValues != 0 && strings.ToUpper(args[i]) == "NOVALUES" {
+			options.noValues = true
+			i--
+			continue
+		}
+		if i+1 >= len(args) {
+			return scanOptions{}, errors.New(protocol.SYNTAX_ERROR)
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			options.match = args[i+1]
+		case "COUNT":
+			count, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return scanOptions{}, errors.New(protocol.NOT_AN_INTEGER)
+			}
+			if count < 1 {
+				return scanOptions{}, errors.New(protocol.SYNTAX_ERROR)
+			}
+			options.count = count
+		case "TYPE":
+			if allowed&scanAllowType == 0 {
+				return scanOptions{}, errors.New(protocol.SYNTAX_ERROR)
+			}
+			options.valType = strings.ToLower(args[i+1])
+		default:
+			return scanOptions{}, errors.New(protocol.SYNTAX_ERROR)
+		}
+	}
+	return options, nil
+}
+
+// matches reports whether name passes the MATCH filter
+func (o scanOptions) matches(name string) bool {
+	if o.match == "" || o.match == "*" {
+		return true
+	}
+	return glob.Match(o.match, name)
+}
+
+// scanReply builds the two elements reply of the SCAN family
+func scanReply(cursor uint64, elements []string) protocol.Reply {
+	return protocol.Array{
+		protocol.BulkString(strconv.FormatUint(cursor, 10)),
+		protocol.NewStringArray(elements),
+	}
+}
+
+// ScanCommand implements the SCAN command
+type ScanCommand struct{}
+
+// Execute implements Command.
+func (c *ScanCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	options, _ := parseScanOptions(args[1:], scanAllowType)
+
+	cursor, keys := cache.Scan(options.cursor, options.count)
+	filtered := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !options.matches(key) {
+			continue
+		}
+		if options.valType != "" && cache.Type(key) != options.valType {
+			continue
+		}
+		filtered = append(filtered, key)
+	}
+	return scanReply(cursor, filtered)
+}
+
+// Validate implements Command.
+func (c *ScanCommand) Validate(args []string) error {
+	_, err := parseScanOptions(args[1:], scanAllowType)
+	return err
+}
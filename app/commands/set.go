@@ -0,0 +1,532 @@
+package commands
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+)
+
+// getSet returns the set stored at key, nil when the key doesn't exist
+func getSet(cache storage.Cache, key string) (*storage.SetValue, error) {
+	redisValue, exists := cache.Get(key)
+	if !exists {
+		return nil, nil
+	}
+	setValue, ok := redisValue.(*storage.SetValue)
+	if !ok {
+		return nil, protocol.ErrWrongType
+	}
+	return setValue, nil
+}
+
+// getOrCreateSet returns the set stored at key, or a new empty one when the
+// key doesn't exist. A new set isn't stored yet, created tells the caller to
+// store it once members were added so no empty set is left behind. Existing
+// sets are updated in place so they keep their expiration.
+func getOrCreateSet(cache storage.Cache, key string) (setValue *storage.SetValue, created bool, err error) {
+	setValue, err = getSet(cache, key)
+	if err != nil || setValue != nil {
+		return setValue, false, err
+	}
+	return storage.NewSetValue(), true, nil
+}
+
+// membersSet converts members into a set reply
+func membersSet(members []string) protocol.Set {
+	reply := make(protocol.Set, 0, len(members))
+	for _, member := range members {
+		reply = append(reply, protocol.BulkString(member))
+	}
+	return reply
+}
+
+// SAddCommand implements the SADD command
+type SAddCommand struct{}
+
+// Execute implements Command.
+func (c *SAddCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	setValue, created, err := getOrCreateSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	added := setValue.Add(args[2:]...)
+	if created {
+		cache.Set(args[1], setValue)
+	}
+	return protocol.Integer(added)
+}
+
+// Validate implements Command.
+func (c *SAddCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// SRemCommand implements the SREM command
+type SRemCommand struct{}
+
+// Execute implements Command.
+func (c *SRemCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	setValue, err := getSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if setValue == nil {
+		return protocol.Integer(0)
+	}
+	removed := setValue.Remove(args[2:]...)
+	// Like Redis, a set that has been emptied stops existing
+	if setValue.Len() == 0 {
+		cache.Delete(args[1])
+	}
+	return protocol.Integer(removed)
+}
+
+// Validate implements Command.
+func (c *SRemCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// SMembersCommand implements the SMEMBERS command
+type SMembersCommand struct{}
+
+// Execute implements Command.
+func (c *SMembersCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	setValue, err := getSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if setValue == nil {
+		return protocol.Set{}
+	}
+	return membersSet(setValue.Members())
+}
+
+// Validate implements Command.
+func (c *SMembersCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// SIsMemberCommand implements the SISMEMBER and SMISMEMBER commands
+type SIsMemberCommand struct {
+	multiple bool
+}
+
+// Execute implements Command.
+func (c *SIsMemberCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	setValue, err := getSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	reply := make(protocol.Array, 0, len(args)-2)
+	for _, member := range args[2:] {
+		if setValue != nil && setValue.Contains(member) {
+			reply = append(reply, protocol.Integer(1))
+		} else {
+			reply = append(reply, protocol.Integer(0))
+		}
+	}
+	if !c.multiple {
+		return reply[0]
+	}
+	return reply
+}
+
+// Validate implements Command.
+func (c *SIsMemberCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// SCardCommand implements the SCARD command
+type SCardCommand struct{}
+
+// Execute implements Command.
+func (c *SCardCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	setValue, err := getSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if setValue == nil {
+		return protocol.Integer(0)
+	}
+	return protocol.Integer(setValue.Len())
+}
+
+// Validate implements Command.
+func (c *SCardCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// SPopCommand implements the SPOP command
+type SPopCommand struct{}
+
+// Execute implements Command.
+func (c *SPopCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	setValue, err := getSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+
+	count := int64(1)
+	if len(args) == 3 {
+		count, _ = strconv.ParseInt(args[2], 10, 64)
+	}
+	if setValue == nil || count == 0 {
+		if len(args) == 2 {
+			return protocol.NullBulkString{}
+		}
+		return protocol.Set{}
+	}
+
+	popped := setValue.Pop(int(count))
+	// Like Redis, a set that has been emptied stops existing
+	if setValue.Len() == 0 {
+		cache.Delete(args[1])
+	}
+	if len(args) == 2 {
+		return protocol.BulkString(popped[0])
+	}
+	return membersSet(popped)
+}
+
+// Validate implements Command.
+func (c *SPopCommand) Validate(args []string) error {
+	if len(args) > 3 {
+		return errors.New(protocol.SYNTAX_ERROR)
+	}
+	if len(args) == 3 {
+		count, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return errors.New(protocol.NOT_AN_INTEGER)
+		}
+		if count < 0 {
+			return errors.New("value is out of range, must be positive")
+		}
+	}
+	return nil
+}
+
+// Propagate implements Propagator, replicas remove the members that were
+// picked rather than picking their own
+func (c *SPopCommand) Propagate(args []string, reply protocol.Reply) [][]string {
+	command := []string{"SREM", args[1]}
+	switch reply := reply.(type) {
+	case protocol.BulkString:
+		command = append(command, string(reply))
+	case protocol.Set:
+		for _, member := range reply {
+			command = append(command, string(member.(protocol.BulkString)))
+		}
+	}
+	if len(command) == 2 {
+		return nil
+	}
+	return [][]string{command}
+}
+
+// SRandMemberCommand implements the SRANDMEMBER command
+type SRandMemberCommand struct{}
+
+// Execute implements Command.
+func (c *SRandMemberCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	setValue, err := getSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+
+	if len(args) == 2 {
+		if setValue == nil {
+			return protocol.NullBulkString{}
+		}
+		return protocol.BulkString(setValue.Random(1, false)[0])
+	}
+
+	count, _ := strconv.ParseInt(args[2], 10, 64)
+	if setValue == nil || count == 0 {
+		return protocol.Array{}
+	}
+	// A negative count allows the same member to be returned several times
+	if count < 0 {
+		return protocol.NewStringArray(setValue.Random(int(-count), true))
+	}
+	return protocol.NewStringArray(setValue.Random(int(count), false))
+}
+
+// Validate implements Command.
+func (c *SRandMemberCommand) Validate(args []string) error {
+	if len(args) > 3 {
+		return errors.New(protocol.SYNTAX_ERROR)
+	}
+	if len(args) == 3 {
+		count, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return errors.New(protocol.NOT_AN_INTEGER)
+		}
+		// Repeated members are all materialized, keep them bounded
+		if count < -maxRandomRepeats {
+			return errors.New("value is out of range")
+		}
+	}
+	return nil
+}
+
+// SMoveCommand implements the SMOVE command
+type SMoveCommand struct{}
+
+// Execute implements Command.
+func (c *SMoveCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	source, err := getSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	// The destination must hold a set even when nothing ends up moving
+	destination, err := getSet(cache, args[2])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if source == nil {
+		return protocol.Integer(0)
+	}
+	if source == destination {
+		if source.Contains(args[3]) {
+			return protocol.Integer(1)
+		}
+		return protocol.Integer(0)
+	}
+
+	if source.Remove(args[3]) == 0 {
+		return protocol.Integer(0)
+	}
+	if source.Len() == 0 {
+		cache.Delete(args[1])
+	}
+	destination, created, _ := getOrCreateSet(cache, args[2])
+	destination.Add(args[3])
+	if created {
+		cache.Set(args[2], destination)
+	}
+	return protocol.Integer(1)
+}
+
+// Validate implements Command.
+func (c *SMoveCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// setOperation is the algebra applied by SINTER, SUNION, SDIFF and their
+// variants
+type setOperation int
+
+const (
+	setInter setOperation = iota
+	setUnion
+	setDiff
+)
+
+// getSets returns the sets stored at keys, with nil for the missing ones
+func getSets(cache storage.Cache, keys []string) ([]*storage.SetValue, error) {
+	sets := make([]*storage.SetValue, 0, len(keys))
+	for _, key := range keys {
+		setValue, err := getSet(cache, key)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, setValue)
+	}
+	return sets, nil
+}
+
+// combineSets applies op to sets, where nil stands for an empty set. A
+// positive limit stops an intersection once it has that many members.
+func combineSets(op setOperation, sets []*storage.SetValue, limit int) *storage.SetValue {
+	result := storage.NewSetValue()
+	switch op {
+	case setInter:
+		if slices.Contains(sets, nil) {
+			return result
+		}
+		// Walking the smallest set bounds the number of lookups
+		sets = slices.Clone(sets)
+		slices.SortFunc(sets, func(a, b *storage.SetValue) int {
+			return a.Len() - b.Len()
+		})
+	members:
+		for _, member := range sets[0].Members() {
+			for _, other := range sets[1:] {
+				if !other.Contains(member) {
+					continue members
+				}
+			}
+			result.Add(member)
+			if limit > 0 && result.Len() >= limit {
+				break
+			}
+		}
+	case setUnion:
+		for _, setValue := range sets {
+			if setValue != nil {
+				result.Add(setValue.Members()...)
+			}
+		}
+	case setDiff:
+		if sets[0] == nil {
+			return result
+		}
+	candidates:
+		for _, member := range sets[0].Members() {
+			for _, other := range sets[1:] {
+				if other != nil && other.Contains(member) {
+					continue candidates
+				}
+			}
+			result.Add(member)
+		}
+	}
+	return result
+}
+
+// SetOperationCommand implements the SINTER, SUNION and SDIFF commands along
+// with their STORE variants, which save the result at their first argument
+type SetOperationCommand struct {
+	op    setOperation
+	store bool
+}
+
+// Execute implements Command.
+func (c *SetOperationCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	keys := args[1:]
+	if c.store {
+		keys = args[2:]
+	}
+	sets, err := getSets(cache, keys)
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	result := combineSets(c.op, sets, 0)
+	if !c.store {
+		return membersSet(result.Members())
+	}
+
+	// The destination is replaced whatever it held, an empty result deletes it
+	if result.Len() == 0 {
+		cache.Delete(args[1])
+	} else {
+		cache.Set(args[1], result)
+	}
+	return protocol.Integer(result.Len())
+}
+
+// Validate implements Command.
+func (c *SetOperationCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// SInterCardCommand implements the SINTERCARD command
+type SInterCardCommand struct{}
+
+// Execute implements Command.
+func (c *SInterCardCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	numKeys, limit, _ := parseInterCard(args)
+	sets, err := getSets(cache, args[2:2+numKeys])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	return protocol.Integer(combineSets(setInter, sets, limit).Len())
+}
+
+// Validate implements Command.
+func (c *SInterCardCommand) Validate(args []string) error {
+	_, _, err := parseInterCard(args)
+	return err
+}
+
+// KeyPositions implements MovableKeysCommand, numkeys tells how many keys
+// follow it
+func (c *SInterCardCommand) KeyPositions(args []string) []int {
+	return numKeysPositions(args, 1)
+}
+
+// numKeysPositions returns the positions of the keys counted by the numkeys
+// argument at args[at]
+func numKeysPositions(args []string, at int) []int {
+	positions := make([]int, 0)
+	if at >= len(args) {
+		return positions
+	}
+	numKeys, err := strconv.Atoi(args[at])
+	if err != nil {
+		return positions
+	}
+	for i := at + 1; i <= at+numKeys && i < len(args); i++ {
+		positions = append(positions, i)
+	}
+	return positions
+}
+
+// parseInterCard parses "numkeys key [key ...] [LIMIT limit]", the
+// arguments of SINTERCARD and ZINTERCARD
+func parseInterCard(args []string) (numKeys int, limit int, err error) {
+	numKeys, err = strconv.Atoi(args[1])
+	if err != nil {
+		return 0, 0, errors.New(protocol.NOT_AN_INTEGER)
+	}
+	if numKeys <= 0 {
+		return 0, 0, errors.New("numkeys should be greater than 0")
+	}
+	if numKeys > len(args)-2 {
+		return 0, 0, errors.New("Number of keys can't be greater than number of args")
+	}
+	for i := 2 + numKeys; i < len(args); i += 2 {
+		if strings.ToUpper(args[i]) != "LIMIT" || i+1 >= len(args) {
+			return 0, 0, errors.New(protocol.SYNTAX_ERROR)
+		}
+		limit, err = strconv.Atoi(args[i+1])
+		if err != nil {
+			return 0, 0, errors.New(protocol.NOT_AN_INTEGER)
+		}
+		if limit < 0 {
+			return 0, 0, errors.New("LIMIT can't be negative")
+		}
+	}
+	return numKeys, limit, nil
+}
+
+// SScanCommand implements the SSCAN command
+type SScanCommand struct{}
+
+// Execute implements Command.
+func (c *SScanCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	options, _ := parseScanOptions(args[2:], 0)
+	setValue, err := getSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if setValue == nil {
+		return scanReply(0, nil)
+	}
+
+	cursor, members := setValue.Scan(options.cursor, options.count)
+	elements := make([]string, 0, len(members))
+	for _, member := range members {
+		if options.matches(member) {
+			elements = append(elements, member)
+		}
+	}
+	return scanReply(cursor, elements)
+}
+
+// Validate implements Command.
+func (c *SScanCommand) Validate(args []string) error {
+	_, err := parseScanOptions(args[2:], 0)
+	return err
+}
@@ -13,7 +13,7 @@ import (
 // XAddCommand implements the XADD command
 type XAddCommand struct{}
 
-func (c *XAddCommand) Execute(args []string, cache storage.Cache) string {
+func (c *XAddCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
 	streamKey := args[1]
 	streamEntryID := args[2]
 
@@ -36,17 +36,13 @@ func (c *XAddCommand) Execute(args []string, cache storage.Cache) string {
 	newEntryID, err := cache.AddToStream(streamKey, &streamEntry)
 	if err != nil {
 		log.Printf("Error adding to stream %s: %v", streamKey, err)
-		return protocol.BuildError(err.Error())
+		return protocol.ErrorFromErr(err)
 	}
 
-	return protocol.BuildBulkString(newEntryID)
+	return protocol.BulkString(newEntryID)
 }
 
 func (c *XAddCommand) Validate(args []string) error {
-	if len(args) < 5 {
-		return errors.New("wrong number of arguments for 'xadd' command")
-	}
-
 	// Check that we have pairs of field-value arguments
 	if (len(args)-3)%2 != 0 {
 		return errors.New("wrong number of arguments for 'xadd' command")
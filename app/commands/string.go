@@ -2,6 +2,7 @@ package commands
 
 import (
 	"errors"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -13,89 +14,152 @@ import (
 // GetCommand implements the GET command
 type GetCommand struct{}
 
-func (c *GetCommand) Execute(args []string, cache storage.Cache) string {
+func (c *GetCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
 	key := args[1]
 	value, exists := cache.Get(key)
 
 	if !exists {
-		return protocol.BuildBulkString("")
+		return protocol.NullBulkString{}
 	}
 
 	// Type assertion to get string value
 	if stringVal, ok := value.(*storage.StringValue); ok {
-		return protocol.BuildBulkString(stringVal.GetValue())
+		return protocol.BulkString(stringVal.GetValue())
 	}
 
 	if intValue, ok := value.(*storage.IntValue); ok {
-		return protocol.BuildBulkString(strconv.Itoa(intValue.Val))
+		return protocol.BulkString(strconv.Itoa(intValue.Val))
 	}
 
-	return protocol.BuildBulkString("")
+	return protocol.ErrWrongType
 }
 
 func (c *GetCommand) Validate(args []string) error {
-	if len(args) < 2 {
-		return errors.New("wrong number of arguments for 'get' command")
-	}
+	// Arity is checked by the registry
 	return nil
 }
 
 // SetCommand implements the SET command
 type SetCommand struct{}
 
-func (c *SetCommand) Execute(args []string, cache storage.Cache) string {
+// setOptions are the expiration options of SET
+type setOptions struct {
+	// expiresAt is the absolute expiration in unix milliseconds, 0 for none
+	expiresAt int64
+	keepTTL   bool
+	// expireArg is the index of the EX, PX, EXAT or PXAT option, -1 if none
+	expireArg int
+}
+
+func parseSetOptions(args []string, now time.Time) (setOptions, error) {
+	opts := setOptions{expireArg: -1}
+	for i := 3; i < len(args); i++ {
+		option := strings.ToUpper(args[i])
+		switch option {
+		case "KEEPTTL":
+			if opts.expireArg != -1 || opts.keepTTL {
+				return opts, errors.New(protocol.SYNTAX_ERROR)
+			}
+			opts.keepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if opts.expireArg != -1 || opts.keepTTL || i+1 >= len(args) {
+				return opts, errors.New(protocol.SYNTAX_ERROR)
+			}
+			value, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return opts, errors.New(protocol.NOT_AN_INTEGER)
+			}
+
+			invalid := errors.New("invalid expire time in 'set' command")
+			if value <= 0 {
+				return opts, invalid
+			}
+			milliseconds := value
+			if option == "EX" || option == "EXAT" {
+				if value > math.MaxInt64/1000 {
+					return opts, invalid
+				}
+				milliseconds = value * 1000
+			}
+			if option == "EX" || option == "PX" {
+				if milliseconds > math.MaxInt64-now.UnixMilli() {
+					return opts, invalid
+				}
+				milliseconds += now.UnixMilli()
+			}
+			opts.expiresAt = milliseconds
+			opts.expireArg = i
+			i++
+		default:
+			return opts, errors.New(protocol.SYNTAX_ERROR)
+		}
+	}
+	return opts, nil
+}
+
+func (c *SetCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return c.ExecuteAt(args, cache, time.Now())
+}
+
+// ExecuteAt implements TimedCommand.
+func (c *SetCommand) ExecuteAt(args []string, cache storage.Cache, now time.Time) protocol.Reply {
 	key := args[1]
 	value := args[2]
-	var expirationTime time.Time
+	opts, err := parseSetOptions(args, now)
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
 
-	// Check for expiration arguments (SET key value PX milliseconds)
-	if len(args) > 4 && strings.ToUpper(args[3]) == "PX" {
-		expDelta, err := strconv.Atoi(args[4])
-		if err == nil {
-			expirationTime = time.Now().Add(time.Duration(expDelta) * time.Millisecond)
+	var expirationTime time.Time
+	if opts.expiresAt > 0 {
+		expirationTime = time.UnixMilli(opts.expiresAt)
+	} else if opts.keepTTL {
+		if _, exists := cache.Get(key); exists {
+			expirationTime, _ = cache.GetExpiration(key)
 		}
 	}
 
+	// Only canonical integers are stored as IntValue, so values like "007",
+	// "+1" or " 1" come back from GET exactly as they were written
 	val, err := strconv.Atoi(value)
 	var redisValue storage.RedisValue
-	if err != nil {
-		redisValue = c.SetStringValue(key, value, expirationTime)
+	if err != nil || strconv.Itoa(val) != value {
+		redisValue = c.SetStringValue(key, value)
 	} else {
-		redisValue = c.SetIntValue(key, val, expirationTime)
+		redisValue = c.SetIntValue(key, val)
 	}
 
-	cache.Set(key, redisValue)
-	return protocol.BuildSimpleString(protocol.RESPONSE_OK)
+	cache.SetWithExpiration(key, redisValue, expirationTime)
+	return protocol.OK
 }
 
-func (c *SetCommand) SetStringValue(key string, val string, expirationTime time.Time) storage.RedisValue {
+func (c *SetCommand) SetStringValue(key string, val string) storage.RedisValue {
 	return &storage.StringValue{
-		Val:        val,
-		Expiration: expirationTime,
+		Val: val,
 	}
 }
 
-func (c *SetCommand) SetIntValue(key string, val int, expirationTime time.Time) storage.RedisValue {
+func (c *SetCommand) SetIntValue(key string, val int) storage.RedisValue {
 	return &storage.IntValue{
-		Val:        val,
-		Expiration: expirationTime,
+		Val: val,
 	}
 }
 
 func (c *SetCommand) Validate(args []string) error {
-	if len(args) < 3 {
-		return errors.New("wrong number of arguments for 'set' command")
-	}
+	_, err := parseSetOptions(args, time.Now())
+	return err
+}
 
-	// Validate PX syntax if present
-	if len(args) > 3 {
-		if len(args) < 5 || strings.ToUpper(args[3]) != "PX" {
-			return errors.New("syntax error")
-		}
-		if _, err := strconv.Atoi(args[4]); err != nil {
-			return errors.New("value is not an integer or out of range")
-		}
+// PropagateAt implements TimedCommand. Replicas receive the expiration as an
+// absolute PXAT, a relative one would be counted from when they apply it.
+func (c *SetCommand) PropagateAt(args []string, reply protocol.Reply, now time.Time) [][]string {
+	opts, _ := parseSetOptions(args, now)
+	if opts.expireArg == -1 {
+		return [][]string{args}
 	}
-
-	return nil
+	rewritten := make([]string, 0, len(args))
+	rewritten = append(rewritten, args[:opts.expireArg]...)
+	rewritten = append(rewritten, args[opts.expireArg+2:]...)
+	rewritten = append(rewritten, "PXAT", strconv.FormatInt(opts.expiresAt, 10))
+	return [][]string{rewritten}
 }
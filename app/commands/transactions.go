@@ -2,14 +2,19 @@ package commands
 
 import (
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/storage"
 )
 
 type TransactionState struct {
 	InTransaction bool
+	// Aborted is set when a command failed to queue, EXEC then discards the
+	// whole transaction with EXECABORT
+	Aborted       bool
 	QueueCommands []*QueueCommand
 	MaxQueueSize  int
 	StartTime     int64
@@ -32,7 +37,7 @@ func (t *TransactionState) StartTransaction() {
 	t.StartTime = time.Now().UnixNano()
 }
 
-func (t *TransactionState) QueueCommand(Cmd Command, args []string) error {
+func (t *TransactionState) QueueCommand(queueCommand *QueueCommand) error {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
 
@@ -40,28 +45,59 @@ func (t *TransactionState) QueueCommand(Cmd Command, args []string) error {
 		return fmt.Errorf("transaction queue full")
 	}
 
-	queueCommand := QueueCommand{
-		Cmd:       Cmd,
-		Args:      args,
-		Timestamp: time.Now().UnixNano(),
+	t.QueueCommands = append(t.QueueCommands, queueCommand)
+	return nil
+}
+
+// MarkAborted flags the running transaction so EXEC refuses to run it
+func (t *TransactionState) MarkAborted() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.InTransaction {
+		t.Aborted = true
 	}
+}
 
-	t.QueueCommands = append(t.QueueCommands, &queueCommand)
-	return nil
+func (t *TransactionState) IsAborted() bool {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.Aborted
 }
 
-func (t *TransactionState) ExecuteTransaction(cache storage.Cache) [][]string {
+// ExecuteTransaction runs the queued commands and returns their replies along
+// with the commands that have to be propagated to replicas. Each command runs
+// against the database its client has selected at that point, so a SELECT
+// inside the transaction affects the commands queued after it.
+func (t *TransactionState) ExecuteTransaction(databases *storage.Databases, db int) ([]protocol.Reply, [][]string) {
 	t.mutex.RLock()
 	commands := make([]*QueueCommand, len(t.QueueCommands))
 	copy(commands, t.QueueCommands)
 	t.mutex.RUnlock()
 
-	result := make([][]string, 0, t.QueueSize())
+	result := make([]protocol.Reply, 0, t.QueueSize())
+	propagated := make([][]string, 0)
 	for _, queuedCommand := range commands {
-		result = append(result, queuedCommand.Execute(cache))
+		commandDB := queuedCommand.Client.DB
+		queuedCommand.Client.inExec = true
+		reply := queuedCommand.Execute(databases.Get(commandDB))
+		queuedCommand.Client.inExec = false
+		if writes := queuedCommand.Propagation(reply); len(writes) > 0 {
+			// Replicas need to follow along when the transaction switched
+			// databases
+			if commandDB != db {
+				propagated = append(propagated, []string{"SELECT", strconv.Itoa(commandDB)})
+				db = commandDB
+			}
+			propagated = append(propagated, writes...)
+		}
+		if reply == nil {
+			// Every queued command needs a slot in the EXEC reply
+			reply = protocol.NullBulkString{}
+		}
+		result = append(result, reply)
 	}
 
-	return result
+	return result, propagated
 }
 
 func (t *TransactionState) GetQueueCommands() []*QueueCommand {
@@ -74,6 +110,7 @@ func (t *TransactionState) EndTransaction() {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
 	t.InTransaction = false
+	t.Aborted = false
 	t.QueueCommands = t.QueueCommands[:0]
 	t.StartTime = 0
 }
@@ -96,6 +133,7 @@ func (t *TransactionState) Reset() bool {
 	defer t.mutex.Unlock()
 
 	t.InTransaction = false
+	t.Aborted = false
 	t.QueueCommands = nil
 	t.StartTime = 0
 	return true
@@ -13,36 +13,41 @@ import (
 type WaitCommand struct{}
 
 // Execute implements Command.
-func (w *WaitCommand) Execute(args []string, cache storage.Cache) string {
-	return "This method of `wait` shouldn't be called"
+func (w *WaitCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return protocol.NewError("This method of `wait` shouldn't be called")
 }
 
 // Validate implements Command.
 func (w *WaitCommand) Validate(args []string) error {
-	if len(args) != 3 {
-		return fmt.Errorf("wrong number of arguments to 'wait' command")
-	}
+	// Arity is checked by the registry
 	return nil
 }
 
-func (w *WaitCommand) ExecuteWithMetadata(args []string, cache storage.Cache, metadata *types.ServerMetadata) []string {
+// Blocks implements BlockingCommand. WAIT waits for the replicas rather than
+// for another client, and neither a disconnection nor CLIENT UNBLOCK cut it
+// short, so its connection isn't handled as a blocked one.
+func (w *WaitCommand) Blocks(args []string) bool {
+	return false
+}
+
+func (w *WaitCommand) ExecuteWithMetadata(args []string, cache storage.Cache, metadata *types.ServerMetadata) protocol.Reply {
 	// Parse arguments: WAIT numreplicas timeout
 	numReplicasStr := args[1]
 	timeoutStr := args[2]
 
 	numReplicas, err := strconv.Atoi(numReplicasStr)
 	if err != nil {
-		return []string{protocol.BuildError("ERR invalid number of replicas")}
+		return protocol.NewError("invalid number of replicas")
 	}
 
 	timeoutMs, err := strconv.Atoi(timeoutStr)
 	if err != nil {
-		return []string{protocol.BuildError("ERR invalid timeout")}
+		return protocol.NewError("invalid timeout")
 	}
 
 	// If no replicas requested, return 0
 	if numReplicas <= 0 {
-		return []string{protocol.BuildInt(0)}
+		return protocol.Integer(0)
 	}
 
 	// Get current number of active replicas
@@ -50,7 +55,7 @@ func (w *WaitCommand) ExecuteWithMetadata(args []string, cache storage.Cache, me
 
 	// If no replicas connected, return 0
 	if activeReplicas == 0 {
-		return []string{protocol.BuildInt(0)}
+		return protocol.Integer(0)
 	}
 
 	// Get current master offset - this is what replicas need to catch up to
@@ -61,7 +66,7 @@ func (w *WaitCommand) ExecuteWithMetadata(args []string, cache storage.Cache, me
 	if currentOffset == 0 {
 		// When no commands have been executed, all connected replicas are considered caught up
 		// Return the total number of active replicas
-		return []string{protocol.BuildInt(activeReplicas)}
+		return protocol.Integer(activeReplicas)
 	}
 
 	// If we need more replicas than we have, wait for all available
@@ -91,7 +96,7 @@ func (w *WaitCommand) ExecuteWithMetadata(args []string, cache storage.Cache, me
 	timeout := time.Duration(timeoutMs) * time.Millisecond
 	select {
 	case count := <-waitReq.ResponseChan:
-		return []string{protocol.BuildInt(count)}
+		return protocol.Integer(count)
 	case <-time.After(timeout):
 		// Timeout - return current count of satisfied replicas
 		satisfiedCount := 0
@@ -100,7 +105,7 @@ func (w *WaitCommand) ExecuteWithMetadata(args []string, cache storage.Cache, me
 				satisfiedCount++
 			}
 		}
-		return []string{protocol.BuildInt(satisfiedCount)}
+		return protocol.Integer(satisfiedCount)
 	}
 }
 
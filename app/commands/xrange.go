@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"errors"
 	"math"
 	"strconv"
 	"strings"
@@ -12,12 +11,12 @@ import (
 
 type XRangeCommand struct{}
 
-func (c *XRangeCommand) Execute(args []string, cache storage.Cache) string {
+func (c *XRangeCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
 	var startEntryID, endEntryID storage.EntryID
 
 	err := c.Validate(args)
 	if err != nil {
-		return protocol.BuildError(err.Error())
+		return protocol.ErrorFromErr(err)
 	}
 
 	key := args[1]
@@ -31,23 +30,25 @@ func (c *XRangeCommand) Execute(args []string, cache storage.Cache) string {
 
 	streamValue, exists := cache.Get(key)
 	if !exists {
-		return protocol.BuildEmptyArray()
+		return protocol.Array{}
 	}
 
-	inRangeEntries := streamValue.(*storage.StreamValue).GetEntriesByRange(&startEntryID, &endEntryID)
+	stream, ok := streamValue.(*storage.StreamValue)
+	if !ok {
+		return protocol.ErrWrongType
+	}
+
+	inRangeEntries := stream.GetEntriesByRange(&startEntryID, &endEntryID)
 	var entries []any
 	for _, entry := range inRangeEntries {
 		entries = append(entries, entry.ToArray())
 	}
 
-	return protocol.BuildArray(entries)
+	return protocol.NewArray(entries)
 }
 
 func (c *XRangeCommand) Validate(args []string) error {
-	if len(args) != 4 {
-		return errors.New("wrong number of arguments for 'XRANGE' command")
-	}
-
+	// Arity is checked by the registry
 	return nil
 }
 
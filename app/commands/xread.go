@@ -3,51 +3,55 @@ package commands
 import (
 	"errors"
 	"log"
+	"math"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/storage"
+	"github.com/codecrafters-io/redis-starter-go/app/types"
 )
 
-type XReadCommand struct{}
-
-func waitForNewData(cache storage.Cache, keys, ids []string) {
-	newDataChan := make(chan bool)
-	go func(cache storage.Cache, keys, ids []string) {
-		ticker := time.NewTicker(10 * time.Millisecond)
-		defer ticker.Stop()
-		for {
-			<-ticker.C
-			for i, key := range keys {
-				redisValueForKey, exists := cache.Get(key)
-				if !exists {
-					continue
-				}
-				var entityId storage.EntryID
-				entityId = *ParseStreamEntryID(ids[i])
-				newLen := len(redisValueForKey.(*storage.StreamValue).GetEntriesGreaterThan(&entityId))
-				if newLen > 0 {
-					newDataChan <- true
-					return
-				}
-			}
-		}
-	}(cache, keys, ids)
+type XReadCommand struct {
+	blocked *BlockedClients
+}
 
-	<-newDataChan
+// parseXReadBlock returns the timeout of "XREAD BLOCK milliseconds ...", and
+// whether the request blocks at all
+func parseXReadBlock(args []string) (time.Duration, bool, error) {
+	if len(args) <= 2 || strings.ToUpper(args[1]) != "BLOCK" {
+		return 0, false, nil
+	}
+	milliseconds, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return 0, true, errors.New("timeout is not an integer or out of range")
+	}
+	if milliseconds < 0 {
+		return 0, true, errors.New("timeout is negative")
+	}
+	if milliseconds > math.MaxInt64/int64(time.Millisecond) {
+		return 0, true, errors.New("timeout is out of range")
+	}
+	return time.Duration(milliseconds) * time.Millisecond, true, nil
 }
 
+// preProcessEntryIds resolves the $ IDs to the last entry of their stream,
+// so that a blocked XREAD only returns the entries added while it waits
 func preProcessEntryIds(keys, ids []string, cache storage.Cache) []string {
 	for i, key := range keys {
+		if ids[i] != "$" {
+			continue
+		}
+		ids[i] = "0-0"
 		redisValueForKey, exists := cache.Get(key)
 		if !exists {
-			ids[i] = "0-0"
 			continue
 		}
-		if ids[i] == "$" {
-			entries := redisValueForKey.(*storage.StreamValue).Entries
+		streamValue, ok := redisValueForKey.(*storage.StreamValue)
+		if ok && len(streamValue.Entries) > 0 {
+			entries := streamValue.Entries
 			lastEntry := entries[len(entries)-1]
 			ids[i] = lastEntry.ID.GetEntryID()
 		}
@@ -55,34 +59,7 @@ func preProcessEntryIds(keys, ids []string, cache storage.Cache) []string {
 	return ids
 }
 
-func handleBlockCommand(args []string, cache storage.Cache) []string {
-	var keys, ids []string
-	if len(args) > 2 && strings.ToUpper(args[1]) == "BLOCK" {
-		waitPeriod, err := strconv.ParseInt(args[2], 10, 64)
-		if err != nil {
-			log.Printf("Invalid BLOCK period: %s", args[2])
-			return args
-		}
-		args = append([]string{args[0]}, args[3:]...) // Remove BLOCK and timeout
-		keysCount := (len(args) - 2) / 2
-		keys = args[2 : 2+keysCount]
-		ids = args[2+keysCount:]
-		ids = preProcessEntryIds(keys, ids, cache)
-		if waitPeriod == 0 {
-			waitForNewData(cache, keys, ids)
-		} else {
-			time.Sleep(time.Duration(waitPeriod) * time.Millisecond)
-		}
-		result := []string{"XREAD", "STREAMS"}
-		result = append(result, keys...)
-		result = append(result, ids...)
-		log.Printf("value of result and ids are %v, %v", result, ids)
-		return result
-	}
-	return args
-}
-
-func processStreams(args []string, cache storage.Cache) string {
+func processStreams(args []string, cache storage.Cache) ([]any, error) {
 	var startEntryId storage.EntryID
 	var entries []any
 	keysCount := (len(args) - 2) / 2
@@ -94,7 +71,10 @@ func processStreams(args []string, cache storage.Cache) string {
 			continue
 		}
 
-		streamValue := redisValueForKey.(*storage.StreamValue)
+		streamValue, ok := redisValueForKey.(*storage.StreamValue)
+		if !ok {
+			return nil, protocol.ErrWrongType
+		}
 
 		// parts := strings.Split(args[2 + i + keysCount], "-")
 		startEntryId = *ParseStreamEntryID(args[2+i+keysCount])
@@ -107,17 +87,63 @@ func processStreams(args []string, cache storage.Cache) string {
 		// entries = append(entries, keysEntries)
 	}
 
-	return protocol.BuildArray(entries)
+	return entries, nil
 
 }
 
 // Execute implements Command.
-func (x *XReadCommand) Execute(args []string, cache storage.Cache) string {
-	log.Println("value of args is: ", args)
-	processedArgs := handleBlockCommand(args, cache)
+func (x *XReadCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return errExecuteUnreachable
+}
+
+// ExecuteWithClient implements ClientAwareCommand. With BLOCK the client
+// waits until one of the streams gets an entry newer than its ID.
+func (x *XReadCommand) ExecuteWithClient(args []string, cache storage.Cache, metadata *types.ServerMetadata, client *Client) protocol.Reply {
+	timeout, blocking, _ := parseXReadBlock(args)
+	streamArgs, _ := preprocessXReadArgs(args)
+	keysCount := (len(streamArgs) - 2) / 2
+	keys := streamArgs[2 : 2+keysCount]
+	if blocking {
+		ids := preProcessEntryIds(keys, slices.Clone(streamArgs[2+keysCount:]), cache)
+		streamArgs = append(append([]string{"XREAD", "STREAMS"}, keys...), ids...)
+	}
+
+	// Every stream is read whatever key woke the client, like a fresh XREAD
+	try := func(cache storage.Cache, key string) protocol.Reply {
+		entries, err := processStreams(streamArgs, cache)
+		if err != nil {
+			return protocol.ErrorFromErr(err)
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		return x.reply(entries, client)
+	}
+	var reply protocol.Reply
+	if blocking {
+		reply = x.blocked.Block(client, cache, keys, timeout, try)
+	} else {
+		reply = try(cache, "")
+	}
+	if reply == nil {
+		return protocol.NullArray{}
+	}
+	return reply
+}
 
-	return processStreams(processedArgs, cache)
+// reply replies with a map of stream key to entries to RESP3 clients, RESP2
+// clients get an array of [key, entries] pairs instead
+func (x *XReadCommand) reply(entries []any, client *Client) protocol.Reply {
+	if !client.IsRESP3() {
+		return protocol.NewArray(entries)
+	}
 
+	reply := make(protocol.Map, 0, len(entries))
+	for _, entry := range entries {
+		pair := protocol.NewArray(entry.([]any))
+		reply = append(reply, protocol.MapEntry{Key: pair[0], Value: pair[1]})
+	}
+	return reply
 }
 
 func preprocessXReadArgs(args []string) ([]string, error) {
@@ -135,6 +161,10 @@ func preprocessXReadArgs(args []string) ([]string, error) {
 
 // Validate implements Command.
 func (x *XReadCommand) Validate(args []string) error {
+	if _, _, err := parseXReadBlock(args); err != nil {
+		return err
+	}
+
 	processedArgs, err := preprocessXReadArgs(args)
 	if err != nil {
 		return err
@@ -150,6 +180,12 @@ func (x *XReadCommand) Validate(args []string) error {
 	return nil
 }
 
+// Blocks implements BlockingCommand, only XREAD BLOCK waits
+func (x *XReadCommand) Blocks(args []string) bool {
+	_, blocking, _ := parseXReadBlock(args)
+	return blocking
+}
+
 func XReadEntriesConversion(key string, entries []storage.StreamEntry) []any {
 	var result []any
 
@@ -162,3 +198,20 @@ func XReadEntriesConversion(key string, entries []storage.StreamEntry) []any {
 		result,
 	}
 }
+
+// KeyPositions implements MovableKeysCommand, the keys are the first half of
+// the arguments following STREAMS
+func (x *XReadCommand) KeyPositions(args []string) []int {
+	positions := make([]int, 0)
+	for i := 1; i < len(args); i++ {
+		if strings.ToUpper(args[i]) != "STREAMS" {
+			continue
+		}
+		keysCount := (len(args) - i - 1) / 2
+		for j := 1; j <= keysCount; j++ {
+			positions = append(positions, i+j)
+		}
+		break
+	}
+	return positions
+}
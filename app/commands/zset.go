@@ -0,0 +1,739 @@
+package commands
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+	"github.com/codecrafters-io/redis-starter-go/app/utility"
+)
+
+// getZSet returns the sorted set stored at key, nil when the key doesn't
+// exist
+func getZSet(cache storage.Cache, key string) (*storage.ZSetValue, error) {
+	redisValue, exists := cache.Get(key)
+	if !exists {
+		return nil, nil
+	}
+	zsetValue, ok := redisValue.(*storage.ZSetValue)
+	if !ok {
+		return nil, protocol.ErrWrongType
+	}
+	return zsetValue, nil
+}
+
+// getOrCreateZSet returns the sorted set stored at key, creating an empty
+// one when the key doesn't exist
+func getOrCreateZSet(cache storage.Cache, key string) (*storage.ZSetValue, error) {
+	zsetValue, err := getZSet(cache, key)
+	if err != nil || zsetValue != nil {
+		return zsetValue, err
+	}
+	zsetValue = storage.NewZSetValue()
+	// Existing sorted sets are updated in place so they keep their expiration
+	cache.Set(key, zsetValue)
+	return zsetValue, nil
+}
+
+// storeZSet saves entries at key, replacing whatever it held. An empty
+// result deletes the key.
+func storeZSet(cache storage.Cache, key string, entries []storage.ZSetEntry) int {
+	if len(entries) == 0 {
+		cache.Delete(key)
+		return 0
+	}
+	zsetValue := storage.NewZSetValue()
+	for _, entry := range entries {
+		zsetValue.Add(entry.Member, entry.Score, 0)
+	}
+	cache.Set(key, zsetValue)
+	return zsetValue.Len()
+}
+
+// zsetEntriesReply converts entries into a reply, pairing every member with
+// its score when withScores is set
+func zsetEntriesReply(entries []storage.ZSetEntry, withScores bool) protocol.Reply {
+	if withScores {
+		reply := make(protocol.Pairs, 0, len(entries))
+		for _, entry := range entries {
+			reply = append(reply, protocol.MapEntry{
+				Key:   protocol.BulkString(entry.Member),
+				Value: protocol.Double(entry.Score),
+			})
+		}
+		return reply
+	}
+	reply := make(protocol.Array, 0, len(entries))
+	for _, entry := range entries {
+		reply = append(reply, protocol.BulkString(entry.Member))
+	}
+	return reply
+}
+
+// parseScore parses a score argument
+func parseScore(arg string) (float64, error) {
+	score, err := utility.ParseFloat(arg)
+	if err != nil {
+		return 0, errors.New("value is not a valid float")
+	}
+	return score, nil
+}
+
+// parseScoreRange parses the bounds of a range of scores, a bound starting
+// with "(" is excluded from the range
+func parseScoreRange(minArg, maxArg string) (storage.ScoreRange, error) {
+	var r storage.ScoreRange
+	var err error
+	parse := func(arg string) (float64, bool, error) {
+		exclusive := strings.HasPrefix(arg, "(")
+		score, err := utility.ParseFloat(strings.TrimPrefix(arg, "("))
+		if err != nil {
+			return 0, false, errors.New("min or max is not a float")
+		}
+		return score, exclusive, nil
+	}
+	if r.Min, r.MinExclusive, err = parse(minArg); err != nil {
+		return r, err
+	}
+	r.Max, r.MaxExclusive, err = parse(maxArg)
+	return r, err
+}
+
+// parseLexRange parses the bounds of a lexicographical range. Bounds start
+// with "[" when included and "(" when excluded, "-" and "+" stand for the
+// lowest and highest possible members.
+func parseLexRange(minArg, maxArg string) (storage.LexRange, error) {
+	var r storage.LexRange
+	var err error
+	parse := func(arg string) (storage.LexBound, error) {
+		switch {
+		case arg == "-":
+			return storage.LexBound{Inf: -1}, nil
+		case arg == "+":
+			return storage.LexBound{Inf: 1}, nil
+		case strings.HasPrefix(arg, "("):
+			return storage.LexBound{Value: arg[1:], Exclusive: true}, nil
+		case strings.HasPrefix(arg, "["):
+			return storage.LexBound{Value: arg[1:]}, nil
+		}
+		return storage.LexBound{}, errors.New("min or max not valid string range item")
+	}
+	if r.Min, err = parse(minArg); err != nil {
+		return r, err
+	}
+	r.Max, err = parse(maxArg)
+	return r, err
+}
+
+// ZAddCommand implements the ZADD command
+type ZAddCommand struct{}
+
+// zaddOptions holds the flags of ZADD
+type zaddOptions struct {
+	// flags are the ones understood by ZSetValue.Add
+	flags int
+	// ch counts updated members along with added ones
+	ch bool
+	// first is the index of the first score
+	first int
+}
+
+// parseZAdd parses "key [NX|XX] [GT|LT] [CH] [INCR] score member [score
+// member ...]"
+func parseZAdd(args []string) (zaddOptions, error) {
+	flags := 0
+	ch := false
+	i := 2
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			flags |= storage.ZAddNX
+		case "XX":
+			flags |= storage.ZAddXX
+		case "GT":
+			flags |= storage.ZAddGT
+		case "LT":
+			flags |= storage.ZAddLT
+		case "INCR":
+			flags |= storage.ZAddIncr
+		case "CH":
+			ch = true
+		default:
+			break options
+		}
+	}
+	pairs := len(args) - i
+	if pairs == 0 || pairs%2 != 0 {
+		return zaddOptions{}, errors.New(protocol.SYNTAX_ERROR)
+	}
+	if flags&storage.ZAddNX != 0 && flags&storage.ZAddXX != 0 {
+		return zaddOptions{}, errors.New("XX and NX options at the same time are not compatible")
+	}
+	if (flags&storage.ZAddGT != 0 && flags&storage.ZAddLT != 0) ||
+		(flags&storage.ZAddNX != 0 && flags&(storage.ZAddGT|storage.ZAddLT) != 0) {
+		return zaddOptions{}, errors.New("GT, LT, and/or NX options at the same time are not compatible")
+	}
+	if flags&storage.ZAddIncr != 0 && pairs > 2 {
+		return zaddOptions{}, errors.New("INCR option supports a single increment-element pair")
+	}
+	for j := i; j < len(args); j += 2 {
+		if _, err := parseScore(args[j]); err != nil {
+			return zaddOptions{}, err
+		}
+	}
+	return zaddOptions{flags: flags, ch: ch, first: i}, nil
+}
+
+// Execute implements Command.
+func (c *ZAddCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	options, _ := parseZAdd(args)
+	incr := options.flags&storage.ZAddIncr != 0
+
+	zsetValue, err := getZSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if zsetValue == nil {
+		// XX never creates the key
+		if options.flags&storage.ZAddXX != 0 {
+			if incr {
+				return protocol.NullBulkString{}
+			}
+			return protocol.Integer(0)
+		}
+		zsetValue, _ = getOrCreateZSet(cache, args[1])
+	}
+
+	changed := 0
+	for i := options.first; i < len(args); i += 2 {
+		score, _ := parseScore(args[i])
+		newScore, outcome, err := zsetValue.Add(args[i+1], score, options.flags)
+		if err != nil {
+			return protocol.ErrorFromErr(err)
+		}
+		if incr {
+			if outcome == storage.ZAddSkipped {
+				return protocol.NullBulkString{}
+			}
+			return protocol.Double(newScore)
+		}
+		if outcome == storage.ZAddAdded || (options.ch && outcome == storage.ZAddUpdated) {
+			changed++
+		}
+	}
+	return protocol.Integer(changed)
+}
+
+// Validate implements Command.
+func (c *ZAddCommand) Validate(args []string) error {
+	_, err := parseZAdd(args)
+	return err
+}
+
+// ZRemCommand implements the ZREM command
+type ZRemCommand struct{}
+
+// Execute implements Command.
+func (c *ZRemCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	zsetValue, err := getZSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if zsetValue == nil {
+		return protocol.Integer(0)
+	}
+	removed := zsetValue.Remove(args[2:]...)
+	// Like Redis, a sorted set that has been emptied stops existing
+	if zsetValue.Len() == 0 {
+		cache.Delete(args[1])
+	}
+	return protocol.Integer(removed)
+}
+
+// Validate implements Command.
+func (c *ZRemCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// ZScoreCommand implements the ZSCORE and ZMSCORE commands
+type ZScoreCommand struct {
+	multiple bool
+}
+
+// Execute implements Command.
+func (c *ZScoreCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	zsetValue, err := getZSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	reply := make(protocol.Array, 0, len(args)-2)
+	for _, member := range args[2:] {
+		if zsetValue == nil {
+			reply = append(reply, protocol.NullBulkString{})
+			continue
+		}
+		score, exists := zsetValue.Score(member)
+		if !exists {
+			reply = append(reply, protocol.NullBulkString{})
+			continue
+		}
+		reply = append(reply, protocol.Double(score))
+	}
+	if !c.multiple {
+		return reply[0]
+	}
+	return reply
+}
+
+// Validate implements Command.
+func (c *ZScoreCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// ZIncrByCommand implements the ZINCRBY command
+type ZIncrByCommand struct{}
+
+// Execute implements Command.
+func (c *ZIncrByCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	delta, _ := parseScore(args[2])
+	zsetValue, err := getOrCreateZSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	score, _, err := zsetValue.Add(args[3], delta, storage.ZAddIncr)
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	return protocol.Double(score)
+}
+
+// Validate implements Command.
+func (c *ZIncrByCommand) Validate(args []string) error {
+	_, err := parseScore(args[2])
+	return err
+}
+
+// ZCardCommand implements the ZCARD command
+type ZCardCommand struct{}
+
+// Execute implements Command.
+func (c *ZCardCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	zsetValue, err := getZSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if zsetValue == nil {
+		return protocol.Integer(0)
+	}
+	return protocol.Integer(zsetValue.Len())
+}
+
+// Validate implements Command.
+func (c *ZCardCommand) Validate(args []string) error {
+	// Arity is checked by the registry
+	return nil
+}
+
+// ZCountCommand implements the ZCOUNT command
+type ZCountCommand struct{}
+
+// Execute implements Command.
+func (c *ZCountCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	r, _ := parseScoreRange(args[2], args[3])
+	zsetValue, err := getZSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if zsetValue == nil {
+		return protocol.Integer(0)
+	}
+	return protocol.Integer(zsetValue.Count(r))
+}
+
+// Validate implements Command.
+func (c *ZCountCommand) Validate(args []string) error {
+	_, err := parseScoreRange(args[2], args[3])
+	return err
+}
+
+// ZRankCommand implements the ZRANK and ZREVRANK commands
+type ZRankCommand struct {
+	reverse bool
+}
+
+// Execute implements Command.
+func (c *ZRankCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	withScore := len(args) == 4
+	zsetValue, err := getZSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	var rank int
+	var score float64
+	exists := false
+	if zsetValue != nil {
+		rank, score, exists = zsetValue.Rank(args[2], c.reverse)
+	}
+	if !exists {
+		if withScore {
+			return protocol.NullArray{}
+		}
+		return protocol.NullBulkString{}
+	}
+	if withScore {
+		return protocol.Array{protocol.Integer(rank), protocol.Double(score)}
+	}
+	return protocol.Integer(rank)
+}
+
+// Validate implements Command.
+func (c *ZRankCommand) Validate(args []string) error {
+	if len(args) > 4 || (len(args) == 4 && strings.ToUpper(args[3]) != "WITHSCORE") {
+		return errors.New(protocol.SYNTAX_ERROR)
+	}
+	return nil
+}
+
+// Ways ZRANGE selects members
+const (
+	zrangeByRank = iota
+	zrangeByScore
+	zrangeByLex
+)
+
+// zrangeSpec holds the arguments shared by ZRANGE and ZRANGESTORE
+type zrangeSpec struct {
+	by         int
+	reverse    bool
+	offset     int
+	limit      int
+	withScores bool
+	// start and stop are the ranks of a range by rank
+	start, stop int
+	scores      storage.ScoreRange
+	lex         storage.LexRange
+}
+
+// parseZRangeSpec parses "start stop [BYSCORE|BYLEX] [REV] [LIMIT offset
+// count] [WITHSCORES]" starting at args[0]
+func parseZRangeSpec(args []string, allowWithScores bool) (zrangeSpec, error) {
+	spec := zrangeSpec{limit: -1}
+	hasLimit := false
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "BYSCORE":
+			if spec.by == zrangeByLex {
+				return spec, errors.New(protocol.SYNTAX_ERROR)
+			}
+			spec.by = zrangeByScore
+		case "BYLEX":
+			if spec.by == zrangeByScore {
+				return spec, errors.New(protocol.SYNTAX_ERROR)
+			}
+			spec.by = zrangeByLex
+		case "REV":
+			spec.reverse = true
+		case "WITHSCORES":
+			if !allowWithScores {
+				return spec, errors.New(protocol.SYNTAX_ERROR)
+			}
+			spec.withScores = true
+		case "LIMIT":
+			if i+2 >= len(args) {
+				return spec, errors.New(protocol.SYNTAX_ERROR)
+			}
+			offset, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return spec, errors.New(protocol.NOT_AN_INTEGER)
+			}
+			limit, err := strconv.Atoi(args[i+2])
+			if err != nil {
+				return spec, errors.New(protocol.NOT_AN_INTEGER)
+			}
+			spec.offset, spec.limit = offset, limit
+			hasLimit = true
+			i += 2
+		default:
+			return spec, errors.New(protocol.SYNTAX_ERROR)
+		}
+	}
+	if hasLimit && spec.by == zrangeByRank {
+		return spec, errors.New("syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+	if spec.withScores && spec.by == zrangeByLex {
+		return spec, errors.New("syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+
+	// With REV, the bounds of score and lex ranges are given highest first
+	minArg, maxArg := args[0], args[1]
+	if spec.reverse {
+		minArg, maxArg = maxArg, minArg
+	}
+	var err error
+	switch spec.by {
+	case zrangeByScore:
+		spec.scores, err = parseScoreRange(minArg, maxArg)
+	case zrangeByLex:
+		spec.lex, err = parseLexRange(minArg, maxArg)
+	default:
+		var start, stop int64
+		start, err = strconv.ParseInt(args[0], 10, 64)
+		if err == nil {
+			stop, err = strconv.ParseInt(args[1], 10, 64)
+		}
+		if err != nil {
+			return spec, errors.New(protocol.NOT_AN_INTEGER)
+		}
+		spec.start, spec.stop = int(start), int(stop)
+	}
+	return spec, err
+}
+
+// run returns the members of zsetValue selected by the spec
+func (s zrangeSpec) run(zsetValue *storage.ZSetValue) []storage.ZSetEntry {
+	switch s.by {
+	case zrangeByScore:
+		return zsetValue.RangeBy(s.scores, s.reverse, s.offset, s.limit)
+	case zrangeByLex:
+		return zsetValue.RangeBy(s.lex, s.reverse, s.offset, s.limit)
+	}
+	return zsetValue.RangeByRank(s.start, s.stop, s.reverse)
+}
+
+// ZRangeCommand implements the ZRANGE command
+type ZRangeCommand struct{}
+
+// Execute implements Command.
+func (c *ZRangeCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	spec, _ := parseZRangeSpec(args[2:], true)
+	zsetValue, err := getZSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if zsetValue == nil {
+		return protocol.Array{}
+	}
+	return zsetEntriesReply(spec.run(zsetValue), spec.withScores)
+}
+
+// Validate implements Command.
+func (c *ZRangeCommand) Validate(args []string) error {
+	_, err := parseZRangeSpec(args[2:], true)
+	return err
+}
+
+// ZRangeStoreCommand implements the ZRANGESTORE command
+type ZRangeStoreCommand struct{}
+
+// Execute implements Command.
+func (c *ZRangeStoreCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	spec, _ := parseZRangeSpec(args[3:], false)
+	zsetValue, err := getZSet(cache, args[2])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	var entries []storage.ZSetEntry
+	if zsetValue != nil {
+		entries = spec.run(zsetValue)
+	}
+	return protocol.Integer(storeZSet(cache, args[1], entries))
+}
+
+// Validate implements Command.
+func (c *ZRangeStoreCommand) Validate(args []string) error {
+	_, err := parseZRangeSpec(args[3:], false)
+	return err
+}
+
+// ZPopCommand implements the ZPOPMIN and ZPOPMAX commands
+type ZPopCommand struct {
+	highest bool
+}
+
+// Execute implements Command.
+func (c *ZPopCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	zsetValue, err := getZSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	count := int64(1)
+	if len(args) == 3 {
+		count, _ = strconv.ParseInt(args[2], 10, 64)
+	}
+	if zsetValue == nil || count == 0 {
+		return protocol.Array{}
+	}
+
+	popped := zsetValue.Pop(int(count), c.highest)
+	// Like Redis, a sorted set that has been emptied stops existing
+	if zsetValue.Len() == 0 {
+		cache.Delete(args[1])
+	}
+	// Without a count the member and its score come as a flat pair
+	if len(args) == 2 {
+		return protocol.Array{protocol.BulkString(popped[0].Member), protocol.Double(popped[0].Score)}
+	}
+	return zsetEntriesReply(popped, true)
+}
+
+// Validate implements Command.
+func (c *ZPopCommand) Validate(args []string) error {
+	if len(args) > 3 {
+		return errors.New(protocol.SYNTAX_ERROR)
+	}
+	if len(args) == 3 {
+		count, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return errors.New(protocol.NOT_AN_INTEGER)
+		}
+		if count < 0 {
+			return errors.New("value is out of range, must be positive")
+		}
+	}
+	return nil
+}
+
+// ZRandMemberCommand implements the ZRANDMEMBER command
+type ZRandMemberCommand struct{}
+
+// Execute implements Command.
+func (c *ZRandMemberCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	zsetValue, err := getZSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+
+	if len(args) == 2 {
+		if zsetValue == nil {
+			return protocol.NullBulkString{}
+		}
+		return protocol.BulkString(zsetValue.Random(1, false)[0].Member)
+	}
+
+	count, _ := strconv.ParseInt(args[2], 10, 64)
+	withScores := len(args) == 4
+	if zsetValue == nil || count == 0 {
+		return protocol.Array{}
+	}
+	// A negative count allows the same member to be returned several times
+	if count < 0 {
+		return zsetEntriesReply(zsetValue.Random(int(-count), true), withScores)
+	}
+	return zsetEntriesReply(zsetValue.Random(int(count), false), withScores)
+}
+
+// Validate implements Command.
+func (c *ZRandMemberCommand) Validate(args []string) error {
+	if len(args) > 4 {
+		return errors.New(protocol.SYNTAX_ERROR)
+	}
+	if len(args) >= 3 {
+		count, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return errors.New(protocol.NOT_AN_INTEGER)
+		}
+		// Repeated members are all materialized, keep them bounded
+		if count < -maxRandomRepeats {
+			return errors.New("value is out of range")
+		}
+	}
+	if len(args) == 4 && strings.ToUpper(args[3]) != "WITHSCORES" {
+		return errors.New(protocol.SYNTAX_ERROR)
+	}
+	return nil
+}
+
+// ZRemRangeCommand implements the ZREMRANGEBYRANK, ZREMRANGEBYSCORE and
+// ZREMRANGEBYLEX commands
+type ZRemRangeCommand struct {
+	by int
+}
+
+// parse parses the bounds of the range to remove
+func (c *ZRemRangeCommand) parse(args []string) (zrangeSpec, error) {
+	spec := zrangeSpec{by: c.by, limit: -1}
+	var err error
+	switch c.by {
+	case zrangeByScore:
+		spec.scores, err = parseScoreRange(args[2], args[3])
+	case zrangeByLex:
+		spec.lex, err = parseLexRange(args[2], args[3])
+	default:
+		var start, stop int64
+		start, err = strconv.ParseInt(args[2], 10, 64)
+		if err == nil {
+			stop, err = strconv.ParseInt(args[3], 10, 64)
+		}
+		if err != nil {
+			return spec, errors.New(protocol.NOT_AN_INTEGER)
+		}
+		spec.start, spec.stop = int(start), int(stop)
+	}
+	return spec, err
+}
+
+// Execute implements Command.
+func (c *ZRemRangeCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	spec, _ := c.parse(args)
+	zsetValue, err := getZSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if zsetValue == nil {
+		return protocol.Integer(0)
+	}
+	var removed int
+	switch c.by {
+	case zrangeByScore:
+		removed = zsetValue.RemoveRangeBy(spec.scores)
+	case zrangeByLex:
+		removed = zsetValue.RemoveRangeBy(spec.lex)
+	default:
+		removed = zsetValue.RemoveRangeByRank(spec.start, spec.stop)
+	}
+	if zsetValue.Len() == 0 {
+		cache.Delete(args[1])
+	}
+	return protocol.Integer(removed)
+}
+
+// Validate implements Command.
+func (c *ZRemRangeCommand) Validate(args []string) error {
+	_, err := c.parse(args)
+	return err
+}
+
+// ZScanCommand implements the ZSCAN command
+type ZScanCommand struct{}
+
+// Execute implements Command.
+func (c *ZScanCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	options, _ := parseScanOptions(args[2:], 0)
+	zsetValue, err := getZSet(cache, args[1])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	if zsetValue == nil {
+		return scanReply(0, nil)
+	}
+
+	cursor, entries := zsetValue.Scan(options.cursor, options.count)
+	elements := make([]string, 0, len(entries)*2)
+	for _, entry := range entries {
+		if options.matches(entry.Member) {
+			elements = append(elements, entry.Member, protocol.FormatDouble(entry.Score))
+		}
+	}
+	return scanReply(cursor, elements)
+}
+
+// Validate implements Command.
+func (c *ZScanCommand) Validate(args []string) error {
+	_, err := parseScanOptions(args[2:], 0)
+	return err
+}
@@ -0,0 +1,312 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+	"github.com/codecrafters-io/redis-starter-go/app/utility"
+)
+
+// zsetAggregate tells how ZUNION and ZINTER combine the scores a member has
+// in several inputs
+type zsetAggregate int
+
+const (
+	aggregateSum zsetAggregate = iota
+	aggregateMin
+	aggregateMax
+)
+
+func (a zsetAggregate) apply(x, y float64) float64 {
+	switch a {
+	case aggregateMin:
+		return math.Min(x, y)
+	case aggregateMax:
+		return math.Max(x, y)
+	}
+	// Like Redis, inf + -inf is 0 rather than NaN
+	if sum := x + y; !math.IsNaN(sum) {
+		return sum
+	}
+	return 0
+}
+
+// zsetInput is a source of the sorted set algebra. Plain sets are accepted
+// too, their members count with a score of 1.
+type zsetInput struct {
+	len     int
+	entries func() []storage.ZSetEntry
+	score   func(member string) (float64, bool)
+	weight  float64
+}
+
+// weighted returns score multiplied by the weight of the input
+func (in *zsetInput) weighted(score float64) float64 {
+	// Like Redis, 0 * inf is 0 rather than NaN
+	if result := score * in.weight; !math.IsNaN(result) {
+		return result
+	}
+	return 0
+}
+
+// loadZSetInputs returns the inputs stored at keys, with nil for the
+// missing ones
+func loadZSetInputs(cache storage.Cache, keys []string) ([]*zsetInput, error) {
+	inputs := make([]*zsetInput, 0, len(keys))
+	for _, key := range keys {
+		redisValue, exists := cache.Get(key)
+		if !exists {
+			inputs = append(inputs, nil)
+			continue
+		}
+		switch value := redisValue.(type) {
+		case *storage.ZSetValue:
+			inputs = append(inputs, &zsetInput{
+				len:     value.Len(),
+				entries: value.Entries,
+				score:   value.Score,
+				weight:  1,
+			})
+		case *storage.SetValue:
+			inputs = append(inputs, &zsetInput{
+				len: value.Len(),
+				entries: func() []storage.ZSetEntry {
+					members := value.Members()
+					entries := make([]storage.ZSetEntry, 0, len(members))
+					for _, member := range members {
+						entries = append(entries, storage.ZSetEntry{Member: member, Score: 1})
+					}
+					return entries
+				},
+				score: func(member string) (float64, bool) {
+					return 1, value.Contains(member)
+				},
+				weight: 1,
+			})
+		default:
+			return nil, protocol.ErrWrongType
+		}
+	}
+	return inputs, nil
+}
+
+// combineZSets applies op to inputs, where nil stands for an empty input. A
+// positive limit stops an intersection once it has that many members.
+func combineZSets(op setOperation, inputs []*zsetInput, aggregate zsetAggregate, limit int) *storage.ZSetValue {
+	result := storage.NewZSetValue()
+	switch op {
+	case setInter:
+		if slices.ContainsFunc(inputs, func(in *zsetInput) bool { return in == nil || in.len == 0 }) {
+			return result
+		}
+		// Walking the smallest input bounds the number of lookups
+		inputs = slices.Clone(inputs)
+		slices.SortFunc(inputs, func(a, b *zsetInput) int {
+			return a.len - b.len
+		})
+	members:
+		for _, entry := range inputs[0].entries() {
+			score := inputs[0].weighted(entry.Score)
+			for _, other := range inputs[1:] {
+				otherScore, exists := other.score(entry.Member)
+				if !exists {
+					continue members
+				}
+				score = aggregate.apply(score, other.weighted(otherScore))
+			}
+			result.Add(entry.Member, score, 0)
+			if limit > 0 && result.Len() >= limit {
+				break
+			}
+		}
+	case setUnion:
+		scores := make(map[string]float64)
+		for _, in := range inputs {
+			if in == nil {
+				continue
+			}
+			for _, entry := range in.entries() {
+				score := in.weighted(entry.Score)
+				if current, exists := scores[entry.Member]; exists {
+					score = aggregate.apply(current, score)
+				}
+				scores[entry.Member] = score
+			}
+		}
+		for member, score := range scores {
+			result.Add(member, score, 0)
+		}
+	case setDiff:
+		if inputs[0] == nil {
+			return result
+		}
+	candidates:
+		for _, entry := range inputs[0].entries() {
+			for _, other := range inputs[1:] {
+				if other == nil {
+					continue
+				}
+				if _, exists := other.score(entry.Member); exists {
+					continue candidates
+				}
+			}
+			result.Add(entry.Member, entry.Score, 0)
+		}
+	}
+	return result
+}
+
+// zsetOperationSpec holds the arguments of ZUNION, ZINTER, ZDIFF and their
+// STORE variants
+type zsetOperationSpec struct {
+	keys       []string
+	weights    []float64
+	aggregate  zsetAggregate
+	withScores bool
+}
+
+// ZSetOperationCommand implements the ZUNION, ZINTER and ZDIFF commands along
+// with their STORE variants, which save the result at their first argument
+type ZSetOperationCommand struct {
+	op    setOperation
+	store bool
+}
+
+// numKeysAt returns the position of the numkeys argument
+func (c *ZSetOperationCommand) numKeysAt() int {
+	if c.store {
+		return 2
+	}
+	return 1
+}
+
+// parse parses "numkeys key [key ...] [WEIGHTS weight [weight ...]]
+// [AGGREGATE SUM|MIN|MAX] [WITHSCORES]", ZDIFF only accepts WITHSCORES and
+// the STORE variants don't accept it
+func (c *ZSetOperationCommand) parse(args []string) (zsetOperationSpec, error) {
+	spec := zsetOperationSpec{}
+	at := c.numKeysAt()
+	numKeys, err := strconv.Atoi(args[at])
+	if err != nil {
+		return spec, errors.New(protocol.NOT_AN_INTEGER)
+	}
+	if numKeys <= 0 {
+		return spec, fmt.Errorf("at least 1 input key is needed for '%s' command", strings.ToLower(args[0]))
+	}
+	if numKeys > len(args)-at-1 {
+		return spec, errors.New(protocol.SYNTAX_ERROR)
+	}
+	spec.keys = args[at+1 : at+1+numKeys]
+
+	for i := at + 1 + numKeys; i < len(args); i++ {
+		option := strings.ToUpper(args[i])
+		switch {
+		case option == "WEIGHTS" && c.op != setDiff:
+			if i+numKeys >= len(args) {
+				return spec, errors.New(protocol.SYNTAX_ERROR)
+			}
+			spec.weights = make([]float64, 0, numKeys)
+			for _, arg := range args[i+1 : i+1+numKeys] {
+				weight, err := utility.ParseFloat(arg)
+				if err != nil {
+					return spec, errors.New("weight value is not a float")
+				}
+				spec.weights = append(spec.weights, weight)
+			}
+			i += numKeys
+		case option == "AGGREGATE" && c.op != setDiff:
+			if i+1 >= len(args) {
+				return spec, errors.New(protocol.SYNTAX_ERROR)
+			}
+			switch strings.ToUpper(args[i+1]) {
+			case "SUM":
+				spec.aggregate = aggregateSum
+			case "MIN":
+				spec.aggregate = aggregateMin
+			case "MAX":
+				spec.aggregate = aggregateMax
+			default:
+				return spec, errors.New(protocol.SYNTAX_ERROR)
+			}
+			i++
+		case option == "WITHSCORES" && !c.store:
+			spec.withScores = true
+		default:
+			return spec, errors.New(protocol.SYNTAX_ERROR)
+		}
+	}
+	return spec, nil
+}
+
+// Execute implements Command.
+func (c *ZSetOperationCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	spec, _ := c.parse(args)
+	inputs, err := loadZSetInputs(cache, spec.keys)
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	for i, weight := range spec.weights {
+		if inputs[i] != nil {
+			inputs[i].weight = weight
+		}
+	}
+	result := combineZSets(c.op, inputs, spec.aggregate, 0)
+	if !c.store {
+		return zsetEntriesReply(result.Entries(), spec.withScores)
+	}
+
+	// The destination is replaced whatever it held, an empty result deletes it
+	if result.Len() == 0 {
+		cache.Delete(args[1])
+	} else {
+		cache.Set(args[1], result)
+	}
+	return protocol.Integer(result.Len())
+}
+
+// Validate implements Command.
+func (c *ZSetOperationCommand) Validate(args []string) error {
+	_, err := c.parse(args)
+	return err
+}
+
+// KeyPositions implements MovableKeysCommand, numkeys tells how many keys
+// follow it and the STORE variants start with their destination
+func (c *ZSetOperationCommand) KeyPositions(args []string) []int {
+	positions := numKeysPositions(args, c.numKeysAt())
+	if c.store {
+		positions = append([]int{1}, positions...)
+	}
+	return positions
+}
+
+// ZInterCardCommand implements the ZINTERCARD command
+type ZInterCardCommand struct{}
+
+// Execute implements Command.
+func (c *ZInterCardCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	numKeys, limit, _ := parseInterCard(args)
+	inputs, err := loadZSetInputs(cache, args[2:2+numKeys])
+	if err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+	return protocol.Integer(combineZSets(setInter, inputs, aggregateSum, limit).Len())
+}
+
+// Validate implements Command.
+func (c *ZInterCardCommand) Validate(args []string) error {
+	_, _, err := parseInterCard(args)
+	return err
+}
+
+// KeyPositions implements MovableKeysCommand, numkeys tells how many keys
+// follow it
+func (c *ZInterCardCommand) KeyPositions(args []string) []int {
+	return numKeysPositions(args, 1)
+}
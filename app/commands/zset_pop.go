@@ -0,0 +1,242 @@
+package commands
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+	"github.com/codecrafters-io/redis-starter-go/app/types"
+)
+
+// popZSet pops up to count members from the sorted set stored at key, it
+// returns nil when the key doesn't hold a non empty sorted set
+func popZSet(cache storage.Cache, key string, count int, highest bool) []storage.ZSetEntry {
+	zsetValue, err := getZSet(cache, key)
+	if err != nil || zsetValue == nil || zsetValue.Len() == 0 {
+		return nil
+	}
+	popped := zsetValue.Pop(count, highest)
+	// Like Redis, a sorted set that has been emptied stops existing
+	if zsetValue.Len() == 0 {
+		cache.Delete(key)
+	}
+	return popped
+}
+
+// checkZSetKeys returns WRONGTYPE when a key holding something else than a
+// sorted set comes before the first non empty sorted set of keys, which is
+// when Redis reports it
+func checkZSetKeys(cache storage.Cache, keys []string) error {
+	for _, key := range keys {
+		zsetValue, err := getZSet(cache, key)
+		if err != nil {
+			return err
+		}
+		if zsetValue != nil && zsetValue.Len() > 0 {
+			return nil
+		}
+	}
+	return nil
+}
+
+// zpopName returns the command replicas apply to reproduce a pop
+func zpopName(highest bool) string {
+	if highest {
+		return "ZPOPMAX"
+	}
+	return "ZPOPMIN"
+}
+
+// BZPopCommand implements the BZPOPMIN and BZPOPMAX commands
+type BZPopCommand struct {
+	highest bool
+	blocked *BlockedClients
+}
+
+// Execute implements Command.
+func (c *BZPopCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return errExecuteUnreachable
+}
+
+// ExecuteWithClient implements ClientAwareCommand.
+func (c *BZPopCommand) ExecuteWithClient(args []string, cache storage.Cache, metadata *types.ServerMetadata, client *Client) protocol.Reply {
+	timeout, _ := parseBlockTimeout(args[len(args)-1])
+	keys := args[1 : len(args)-1]
+	if err := checkZSetKeys(cache, keys); err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+
+	reply := c.blocked.Block(client, cache, keys, timeout, func(cache storage.Cache, key string) protocol.Reply {
+		popped := popZSet(cache, key, 1, c.highest)
+		if popped == nil {
+			return nil
+		}
+		return protocol.Array{
+			protocol.BulkString(key),
+			protocol.BulkString(popped[0].Member),
+			protocol.Double(popped[0].Score),
+		}
+	})
+	if reply == nil {
+		return protocol.NullArray{}
+	}
+	return reply
+}
+
+// Validate implements Command.
+func (c *BZPopCommand) Validate(args []string) error {
+	_, err := parseBlockTimeout(args[len(args)-1])
+	return err
+}
+
+// Propagate implements Propagator, replicas apply the pop that was served
+// rather than blocking themselves
+func (c *BZPopCommand) Propagate(args []string, reply protocol.Reply) [][]string {
+	popped, ok := reply.(protocol.Array)
+	if !ok || len(popped) != 3 {
+		return nil
+	}
+	key, _ := popped[0].(protocol.BulkString)
+	return [][]string{{zpopName(c.highest), string(key)}}
+}
+
+// zmpopSpec holds the arguments of ZMPOP and BZMPOP
+type zmpopSpec struct {
+	keys    []string
+	highest bool
+	count   int
+}
+
+// parseZMPop parses "numkeys key [key ...] MIN|MAX [COUNT count]" starting
+// at args[at]
+func parseZMPop(args []string, at int) (zmpopSpec, error) {
+	spec := zmpopSpec{count: 1}
+	numKeys, err := strconv.Atoi(args[at])
+	if err != nil || numKeys <= 0 {
+		return spec, errors.New("numkeys should be greater than 0")
+	}
+	// Checked before any arithmetic, a huge numkeys would overflow
+	if numKeys > len(args)-at-1 {
+		return spec, errors.New(protocol.SYNTAX_ERROR)
+	}
+	where := at + numKeys + 1
+	if where >= len(args) {
+		return spec, errors.New(protocol.SYNTAX_ERROR)
+	}
+	spec.keys = args[at+1 : where]
+
+	switch strings.ToUpper(args[where]) {
+	case "MIN":
+	case "MAX":
+		spec.highest = true
+	default:
+		return spec, errors.New(protocol.SYNTAX_ERROR)
+	}
+
+	hasCount := false
+	for i := where + 1; i < len(args); i += 2 {
+		if strings.ToUpper(args[i]) != "COUNT" || hasCount || i+1 >= len(args) {
+			return spec, errors.New(protocol.SYNTAX_ERROR)
+		}
+		count, err := strconv.Atoi(args[i+1])
+		if err != nil || count <= 0 {
+			return spec, errors.New("count should be greater than 0")
+		}
+		spec.count = count
+		hasCount = true
+	}
+	return spec, nil
+}
+
+// zmpopReply builds the reply of ZMPOP, the key along with the popped
+// members and their scores
+func zmpopReply(key string, popped []storage.ZSetEntry) protocol.Reply {
+	entries := make(protocol.Array, 0, len(popped))
+	for _, entry := range popped {
+		entries = append(entries, protocol.Array{protocol.BulkString(entry.Member), protocol.Double(entry.Score)})
+	}
+	return protocol.Array{protocol.BulkString(key), entries}
+}
+
+// ZMPopCommand implements the ZMPOP and BZMPOP commands
+type ZMPopCommand struct {
+	blocking bool
+	blocked  *BlockedClients
+}
+
+// numKeysAt returns the position of the numkeys argument, BZMPOP starts
+// with its timeout
+func (c *ZMPopCommand) numKeysAt() int {
+	if c.blocking {
+		return 2
+	}
+	return 1
+}
+
+// Execute implements Command.
+func (c *ZMPopCommand) Execute(args []string, cache storage.Cache) protocol.Reply {
+	return errExecuteUnreachable
+}
+
+// ExecuteWithClient implements ClientAwareCommand.
+func (c *ZMPopCommand) ExecuteWithClient(args []string, cache storage.Cache, metadata *types.ServerMetadata, client *Client) protocol.Reply {
+	spec, _ := parseZMPop(args, c.numKeysAt())
+	if err := checkZSetKeys(cache, spec.keys); err != nil {
+		return protocol.ErrorFromErr(err)
+	}
+
+	try := func(cache storage.Cache, key string) protocol.Reply {
+		popped := popZSet(cache, key, spec.count, spec.highest)
+		if popped == nil {
+			return nil
+		}
+		return zmpopReply(key, popped)
+	}
+	var reply protocol.Reply
+	if c.blocking {
+		timeout, _ := parseBlockTimeout(args[1])
+		reply = c.blocked.Block(client, cache, spec.keys, timeout, try)
+	} else {
+		for _, key := range spec.keys {
+			if reply = try(cache, key); reply != nil {
+				break
+			}
+		}
+	}
+	if reply == nil {
+		return protocol.NullArray{}
+	}
+	return reply
+}
+
+// Validate implements Command.
+func (c *ZMPopCommand) Validate(args []string) error {
+	if c.blocking {
+		if _, err := parseBlockTimeout(args[1]); err != nil {
+			return err
+		}
+	}
+	_, err := parseZMPop(args, c.numKeysAt())
+	return err
+}
+
+// KeyPositions implements MovableKeysCommand, numkeys tells how many keys
+// follow it
+func (c *ZMPopCommand) KeyPositions(args []string) []int {
+	return numKeysPositions(args, c.numKeysAt())
+}
+
+// Propagate implements Propagator, replicas pop from the key that was
+// served with the count that was actually popped
+func (c *ZMPopCommand) Propagate(args []string, reply protocol.Reply) [][]string {
+	popped, ok := reply.(protocol.Array)
+	if !ok || len(popped) != 2 {
+		return nil
+	}
+	spec, _ := parseZMPop(args, c.numKeysAt())
+	key, _ := popped[0].(protocol.BulkString)
+	entries, _ := popped[1].(protocol.Array)
+	return [][]string{{zpopName(spec.highest), string(key), strconv.Itoa(len(entries))}}
+}
@@ -9,6 +9,7 @@ const (
 	// Redis protocol constants
 	MaxBulkStringLength = 512 * 1024 * 1024 // 512MB
 	MaxArrayLength      = 1024 * 1024       // 1M elements
+	MaxInlineLength     = 64 * 1024         // 64KB per inline request or header line
 
 	// Server limits
 	MaxConnections      = 10000
@@ -16,4 +17,5 @@ const (
 	WriteTimeout        = 30 // seconds
 	CONFIG_DB_FILE_NAME = ""
 	CONFIG_DIR          = ""
+	DefaultDatabases    = 16
 )
@@ -0,0 +1,125 @@
+// Package glob implements the glob-style patterns of Redis, used to match
+// key names and configuration parameters.
+//
+// The supported syntax is:
+//   - "*" matches any sequence of characters, including none
+//   - "?" matches exactly one character
+//   - "[abc]" matches one of the characters in the brackets
+//   - "[^abc]" matches one character that isn't in the brackets
+//   - "[a-z]" matches one character in the range, bounds may be reversed
+//   - "\x" matches x literally, also inside brackets
+//
+// An unterminated bracket extends to the end of the pattern and a trailing
+// backslash matches itself, like Redis does.
+package glob
+
+// Match reports whether s matches pattern
+func Match(pattern, s string) bool {
+	return match(pattern, s, false)
+}
+
+// MatchNoCase reports whether s matches pattern, ignoring ASCII case
+func MatchNoCase(pattern, s string) bool {
+	return match(pattern, s, true)
+}
+
+// match walks the pattern and s together. Every token but * consumes exactly
+// one character, so when a token fails it is enough to backtrack to the last
+// * and let it swallow one more character, earlier stars never need to be
+// revisited. This keeps matching O(len(pattern) * len(s)) on any input.
+func match(pattern, s string, nocase bool) bool {
+	p, i := 0, 0
+	starP, starI := -1, -1
+	for i < len(s) {
+		if p < len(pattern) && pattern[p] == '*' {
+			for p < len(pattern) && pattern[p] == '*' {
+				p++
+			}
+			if p == len(pattern) {
+				return true
+			}
+			starP, starI = p, i
+			continue
+		}
+		if p < len(pattern) {
+			if matched, next := matchToken(pattern, p, s[i], nocase); matched {
+				p = next
+				i++
+				continue
+			}
+		}
+		if starP < 0 {
+			return false
+		}
+		starI++
+		p, i = starP, starI
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchToken matches c against the token starting at pattern[p] and returns
+// the index of the next token
+func matchToken(pattern string, p int, c byte, nocase bool) (bool, int) {
+	switch pattern[p] {
+	case '?':
+		return true, p + 1
+	case '[':
+		return matchClass(pattern, p+1, c, nocase)
+	case '\\':
+		if p+1 < len(pattern) {
+			p++
+		}
+	}
+	return equal(pattern[p], c, nocase), p + 1
+}
+
+// matchClass matches c against the bracket expression whose content starts
+// at pattern[p]
+func matchClass(pattern string, p int, c byte, nocase bool) (bool, int) {
+	negate := p < len(pattern) && pattern[p] == '^'
+	if negate {
+		p++
+	}
+	matched := false
+	for ; p < len(pattern) && pattern[p] != ']'; p++ {
+		switch {
+		case pattern[p] == '\\' && p+1 < len(pattern):
+			p++
+			matched = matched || pattern[p] == c
+		case p+2 < len(pattern) && pattern[p+1] == '-':
+			start, end := pattern[p], pattern[p+2]
+			if start > end {
+				start, end = end, start
+			}
+			char := c
+			if nocase {
+				start, end, char = lower(start), lower(end), lower(c)
+			}
+			matched = matched || (char >= start && char <= end)
+			p += 2
+		default:
+			matched = matched || equal(pattern[p], c, nocase)
+		}
+	}
+	if negate {
+		matched = !matched
+	}
+	return matched, min(p+1, len(pattern))
+}
+
+func equal(a, b byte, nocase bool) bool {
+	if nocase {
+		return lower(a) == lower(b)
+	}
+	return a == b
+}
+
+func lower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
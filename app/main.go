@@ -13,6 +13,7 @@ import (
 	"github.com/codecrafters-io/redis-starter-go/app/config"
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/server"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
 	"github.com/codecrafters-io/redis-starter-go/app/types"
 	"github.com/codecrafters-io/redis-starter-go/app/utility"
 )
@@ -71,12 +72,30 @@ func Handshake(address string, sInstancePort string) (net.Conn, error) {
 func ProcessServerMetadata() (*types.ServerMetadata, []string) {
 	var portFlag, replicaOf, role, master_replid, masterPort string
 	var dir, dbFileName string
+	var databases int
+	var maxMemory, maxMemoryPolicy string
 
 	flag.StringVar(&portFlag, "port", "6379", "This flag is used for specifying the port")
 	flag.StringVar(&replicaOf, "replicaof", "", "This flag is used to metion the master redis instance")
 	flag.StringVar(&dir, "dir", "", "This flag is used to configuring RDB directory")
 	flag.StringVar(&dbFileName, "dbfilename", "", "This flag is used to configuring RDB filename")
+	flag.IntVar(&databases, "databases", config.DefaultDatabases, "This flag is used for setting the number of databases")
+	flag.StringVar(&maxMemory, "maxmemory", "0", "This flag is used for limiting the memory used by keys, 0 means no limit")
+	flag.StringVar(&maxMemoryPolicy, "maxmemory-policy", string(storage.NoEviction), "This flag is used for choosing which keys are evicted at the maxmemory limit")
 	flag.Parse()
+	if databases < 1 {
+		fmt.Println("databases must be at least 1")
+		os.Exit(1)
+	}
+	maxMemoryBytes, err := utility.ParseMemory(maxMemory)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if _, err := storage.ParseEvictionPolicy(maxMemoryPolicy); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 	if replicaOf == "" {
 		role = "master"
 		master_replid = utility.GenerateRandomString(40)
@@ -90,6 +109,9 @@ func ProcessServerMetadata() (*types.ServerMetadata, []string) {
 	metadata.MasterReplID = master_replid
 	metadata.Dir = dir
 	metadata.DbFileName = dbFileName
+	metadata.Databases = databases
+	metadata.MaxMemory = maxMemoryBytes
+	metadata.MaxMemoryPolicy = maxMemoryPolicy
 
 	return metadata, []string{masterPort, portFlag}
 }
@@ -1,5 +1,10 @@
 package protocol
 
+const (
+	RESP2 = 2
+	RESP3 = 3
+)
+
 const (
 	CRLF                  = "\r\n"
 	RESPONSE_OK           = "OK"
@@ -13,6 +18,8 @@ const (
 	EXEC_BEFORE_MULTI     = "EXEC without MULTI"
 	MULTI_IN_MULTI        = "MULTI calls can not be nested"
 	DISCARD_WITHOUT_MULTI = "DISCARD without MULTI"
+	DB_INDEX_OUT_OF_RANGE = "DB index is out of range"
+	SYNTAX_ERROR          = "syntax error"
 )
 
 var (
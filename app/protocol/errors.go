@@ -0,0 +1,77 @@
+package protocol
+
+import (
+	"errors"
+	"strings"
+)
+
+// Error codes are the first word of an error reply, client libraries branch
+// on them to tell failures apart
+const (
+	ERR_CODE_GENERIC    = "ERR"
+	ERR_CODE_WRONGTYPE  = "WRONGTYPE"
+	ERR_CODE_NOSCRIPT   = "NOSCRIPT"
+	ERR_CODE_EXECABORT  = "EXECABORT"
+	ERR_CODE_MOVED      = "MOVED"
+	ERR_CODE_READONLY   = "READONLY"
+	ERR_CODE_NOAUTH     = "NOAUTH"
+	ERR_CODE_BUSYGROUP  = "BUSYGROUP"
+	ERR_CODE_NOPROTO    = "NOPROTO"
+	ERR_CODE_WRONGPASS  = "WRONGPASS"
+	ERR_CODE_UNBLOCKED  = "UNBLOCKED"
+	ERR_CODE_NOREPLICAS = "NOREPLICAS"
+	ERR_CODE_OOM        = "OOM"
+)
+
+// Error is an error reply carrying a code and a message. It is both a Reply
+// and an error, so storage and validation code can return it as is and the
+// code survives all the way to the client.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e Error) Error() string {
+	return e.Code + " " + e.Message
+}
+
+// AppendTo writes the error as a single line, messages may quote user input
+// so their CR and LF become spaces like in the addReplyError of Redis
+func (e Error) AppendTo(buf []byte, protover int) []byte {
+	return appendLine(buf, '-', errorLineReplacer.Replace(e.Error()))
+}
+
+var errorLineReplacer = strings.NewReplacer("\r", " ", "\n", " ")
+
+// NewError builds a generic error reply with the ERR code
+func NewError(msg string) Error {
+	return Error{Code: ERR_CODE_GENERIC, Message: msg}
+}
+
+// NewErrorWithCode builds an error reply with a specific code
+func NewErrorWithCode(code, msg string) Error {
+	return Error{Code: code, Message: msg}
+}
+
+// ErrorFromErr converts any error into an error reply, keeping the code of
+// an Error and falling back to ERR for everything else
+func ErrorFromErr(err error) Error {
+	var replyErr Error
+	if errors.As(err, &replyErr) {
+		return replyErr
+	}
+	return NewError(err.Error())
+}
+
+var (
+	ErrWrongType = NewErrorWithCode(ERR_CODE_WRONGTYPE, "Operation against a key holding the wrong kind of value")
+	ErrExecAbort = NewErrorWithCode(ERR_CODE_EXECABORT, "Transaction discarded because of previous errors.")
+	ErrNoProto   = NewErrorWithCode(ERR_CODE_NOPROTO, "unsupported protocol version")
+	ErrWrongPass = NewErrorWithCode(ERR_CODE_WRONGPASS, "invalid username-password pair or user is disabled.")
+	ErrReadOnly  = NewErrorWithCode(ERR_CODE_READONLY, "You can't write against a read only replica.")
+	ErrOOM       = NewErrorWithCode(ERR_CODE_OOM, "command not allowed when used memory > 'maxmemory'.")
+	ErrNoSuchKey = NewError("no such key")
+	// ErrUnblocked is the reply of a blocked command interrupted by CLIENT
+	// UNBLOCK ... ERROR
+	ErrUnblocked = NewErrorWithCode(ERR_CODE_UNBLOCKED, "client unblocked via CLIENT UNBLOCK")
+)
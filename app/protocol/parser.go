@@ -0,0 +1,246 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/config"
+)
+
+// ProtocolError is returned when the peer sends a request that violates RESP.
+// The stream can't be resynchronized afterwards, so the connection should be
+// closed once the error has been reported to the client.
+type ProtocolError struct {
+	Msg string
+}
+
+func (e *ProtocolError) Error() string {
+	return "Protocol error: " + e.Msg
+}
+
+func newProtocolError(msg string) *ProtocolError {
+	return &ProtocolError{Msg: msg}
+}
+
+// ParseRequest parses a request from the reader. Requests are normally RESP
+// arrays, where every bulk string is read by its declared `$<len>` header so
+// arguments may contain CRLF, surrounding whitespace or arbitrary binary data.
+// Any other line is treated as an inline command (e.g. `PING` typed into
+// telnet). The returned int64 is the number of bytes consumed from the reader.
+func ParseRequest(reader *bufio.Reader) ([]string, error, int64) {
+	var numOfBytes int64
+	header, err := readLine(reader, config.MaxInlineLength)
+	numOfBytes += int64(len(header))
+	if err != nil {
+		return RESP_ZERO_REQUEST, err, 0
+	}
+
+	header = trimLineEnding(header)
+
+	if !strings.HasPrefix(header, "*") {
+		args, err := parseInline(header)
+		if err != nil {
+			return RESP_ZERO_REQUEST, err, 0
+		}
+		return args, nil, numOfBytes
+	}
+
+	count, err := strconv.Atoi(header[1:])
+	if err != nil || count > config.MaxArrayLength {
+		return RESP_ZERO_REQUEST, newProtocolError("invalid multibulk length"), 0
+	}
+	if count <= 0 {
+		// `*0` and `*-1` are valid but carry no command
+		return RESP_ZERO_REQUEST, nil, numOfBytes
+	}
+
+	// Don't trust the header for the allocation size, a bogus length would
+	// otherwise let a single line reserve a huge slice.
+	respRequest := make([]string, 0, min(count, 1024))
+	for i := 0; i < count; i++ {
+		arg, n, err := readBulkString(reader)
+		numOfBytes += n
+		if err != nil {
+			return RESP_ZERO_REQUEST, err, 0
+		}
+		respRequest = append(respRequest, arg)
+	}
+
+	return respRequest, nil, numOfBytes
+}
+
+// bulkPreallocLimit bounds the buffer reserved for a bulk string before its
+// payload arrives
+const bulkPreallocLimit = 64 * 1024
+
+// readBulkString reads a single `$<len>\r\n<data>\r\n` element
+func readBulkString(reader *bufio.Reader) (string, int64, error) {
+	var numOfBytes int64
+	line, err := readLine(reader, config.MaxInlineLength)
+	numOfBytes += int64(len(line))
+	if err != nil {
+		return EMPTY_STRING, numOfBytes, err
+	}
+
+	line = trimLineEnding(line)
+	if !strings.HasPrefix(line, "$") {
+		got := "EOL"
+		if len(line) > 0 {
+			got = line[:1]
+		}
+		return EMPTY_STRING, numOfBytes, newProtocolError("expected '$', got '" + got + "'")
+	}
+
+	length, err := strconv.Atoi(line[1:])
+	if err != nil || length < 0 || length > config.MaxBulkStringLength {
+		return EMPTY_STRING, numOfBytes, newProtocolError("invalid bulk length")
+	}
+
+	// Payload plus the trailing CRLF. The buffer only grows as the data
+	// arrives, a header alone can't make the server reserve a huge slice.
+	var buf bytes.Buffer
+	buf.Grow(min(length+2, bulkPreallocLimit))
+	n, err := io.CopyN(&buf, reader, int64(length+2))
+	numOfBytes += n
+	if err == io.EOF && n > 0 {
+		// Like io.ReadFull, a payload cut short isn't a clean EOF
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return EMPTY_STRING, numOfBytes, err
+	}
+	data := buf.Bytes()
+	if data[length] != '\r' || data[length+1] != '\n' {
+		return EMPTY_STRING, numOfBytes, newProtocolError("bulk string is not terminated by CRLF")
+	}
+
+	return string(data[:length]), numOfBytes, nil
+}
+
+// readLine reads up to and including the next '\n', failing with a protocol
+// error once the line grows past limit bytes instead of buffering it forever.
+func readLine(reader *bufio.Reader, limit int) (string, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > limit {
+			return EMPTY_STRING, newProtocolError("too big inline request")
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return EMPTY_STRING, err
+		}
+		return string(line), nil
+	}
+}
+
+// parseInline splits an inline command into arguments. Arguments are
+// separated by whitespace and may be quoted: double quotes understand the
+// usual escapes (\n, \r, \t, \b, \a, \\, \" and \xHH) while single
+// quotes only understand \'. This mirrors how redis-cli and telnet sessions
+// are interpreted by Redis.
+func parseInline(line string) ([]string, error) {
+	args := make([]string, 0)
+	i := 0
+	for {
+		for i < len(line) && isInlineSpace(line[i]) {
+			i++
+		}
+		if i >= len(line) {
+			return args, nil
+		}
+
+		var arg []byte
+		inDoubleQuotes, inSingleQuotes := false, false
+		for done := false; !done; {
+			if i >= len(line) {
+				if inDoubleQuotes || inSingleQuotes {
+					return nil, newProtocolError("unbalanced quotes in request")
+				}
+				break
+			}
+			c := line[i]
+			switch {
+			case inDoubleQuotes:
+				if c == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHexDigit(line[i+2]) && isHexDigit(line[i+3]) {
+					b, _ := strconv.ParseUint(line[i+2:i+4], 16, 8)
+					arg = append(arg, byte(b))
+					i += 3
+				} else if c == '\\' && i+1 < len(line) {
+					i++
+					arg = append(arg, unescapeInline(line[i]))
+				} else if c == '"' {
+					// The closing quote must be followed by a space or the end of line
+					if i+1 < len(line) && !isInlineSpace(line[i+1]) {
+						return nil, newProtocolError("unbalanced quotes in request")
+					}
+					done = true
+				} else {
+					arg = append(arg, c)
+				}
+			case inSingleQuotes:
+				if c == '\\' && i+1 < len(line) && line[i+1] == '\'' {
+					i++
+					arg = append(arg, '\'')
+				} else if c == '\'' {
+					if i+1 < len(line) && !isInlineSpace(line[i+1]) {
+						return nil, newProtocolError("unbalanced quotes in request")
+					}
+					done = true
+				} else {
+					arg = append(arg, c)
+				}
+			default:
+				switch c {
+				case ' ', '\n', '\r', '\t', 0:
+					done = true
+				case '"':
+					inDoubleQuotes = true
+				case '\'':
+					inSingleQuotes = true
+				default:
+					arg = append(arg, c)
+				}
+			}
+			i++
+		}
+		args = append(args, string(arg))
+	}
+}
+
+func unescapeInline(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'b':
+		return '\b'
+	case 'a':
+		return '\a'
+	default:
+		return c
+	}
+}
+
+func isInlineSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == 0
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// trimLineEnding strips the trailing "\r\n" (or a bare "\n") from a header line
+func trimLineEnding(line string) string {
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r")
+}
@@ -0,0 +1,248 @@
+package protocol
+
+import (
+	"math"
+	"strconv"
+)
+
+// Reply is the typed result of a command. Commands build replies without
+// knowing the wire format, the connection encodes them exactly once for the
+// protocol version its client negotiated. A nil Reply means "send nothing".
+type Reply interface {
+	// AppendTo appends the encoding of the reply for protover to buf
+	AppendTo(buf []byte, protover int) []byte
+}
+
+// Encode serializes a reply for the given protocol version
+func Encode(reply Reply, protover int) []byte {
+	if reply == nil {
+		return nil
+	}
+	return reply.AppendTo(nil, protover)
+}
+
+// SimpleString is a status reply such as +OK
+type SimpleString string
+
+// Integer is a signed 64 bit integer reply
+type Integer int64
+
+// BulkString is a binary safe string reply. BulkString("") is an empty
+// string, which is not the same thing as NullBulkString.
+type BulkString string
+
+// NullBulkString is the nil reply for a missing value
+type NullBulkString struct{}
+
+// NullArray is the nil reply for a missing aggregate, e.g. a timed out XREAD
+type NullArray struct{}
+
+// Array is an ordered list of replies
+type Array []Reply
+
+// MapEntry is a single key/value pair of a Map
+type MapEntry struct {
+	Key   Reply
+	Value Reply
+}
+
+// Map is a RESP3 map, RESP2 clients receive a flat array of keys and values
+type Map []MapEntry
+
+// Pairs is a list of pairs such as the fields and values of HRANDFIELD
+// WITHVALUES. RESP3 clients receive an array of two element arrays, RESP2
+// clients a flat array like for a Map.
+type Pairs []MapEntry
+
+// Set is a RESP3 set, RESP2 clients receive an array
+type Set []Reply
+
+// Double is a RESP3 double, RESP2 clients receive a bulk string
+type Double float64
+
+// Boolean is a RESP3 boolean, RESP2 clients receive 1 or 0
+type Boolean bool
+
+// BigNumber is a RESP3 big number in decimal form, RESP2 clients receive a bulk string
+type BigNumber string
+
+// Verbatim is a RESP3 verbatim string, RESP2 clients receive a bulk string
+type Verbatim struct {
+	Format string
+	Text   string
+}
+
+// Push is a RESP3 out-of-band push frame, RESP2 clients receive an array
+type Push []Reply
+
+func (s SimpleString) AppendTo(buf []byte, protover int) []byte {
+	return appendLine(buf, '+', string(s))
+}
+
+func (i Integer) AppendTo(buf []byte, protover int) []byte {
+	return appendLine(buf, ':', strconv.FormatInt(int64(i), 10))
+}
+
+func (b BulkString) AppendTo(buf []byte, protover int) []byte {
+	return appendBulk(buf, '$', string(b))
+}
+
+func (NullBulkString) AppendTo(buf []byte, protover int) []byte {
+	if protover == RESP3 {
+		return appendLine(buf, '_', EMPTY_STRING)
+	}
+	return appendLine(buf, '$', "-1")
+}
+
+func (NullArray) AppendTo(buf []byte, protover int) []byte {
+	if protover == RESP3 {
+		return appendLine(buf, '_', EMPTY_STRING)
+	}
+	return appendLine(buf, '*', "-1")
+}
+
+func (a Array) AppendTo(buf []byte, protover int) []byte {
+	return appendAggregate(buf, '*', a, protover)
+}
+
+func (m Map) AppendTo(buf []byte, protover int) []byte {
+	if protover == RESP3 {
+		buf = appendLine(buf, '%', strconv.Itoa(len(m)))
+	} else {
+		buf = appendLine(buf, '*', strconv.Itoa(len(m)*2))
+	}
+	for _, entry := range m {
+		buf = entry.Key.AppendTo(buf, protover)
+		buf = entry.Value.AppendTo(buf, protover)
+	}
+	return buf
+}
+
+func (p Pairs) AppendTo(buf []byte, protover int) []byte {
+	if protover != RESP3 {
+		return Map(p).AppendTo(buf, protover)
+	}
+	buf = appendLine(buf, '*', strconv.Itoa(len(p)))
+	for _, pair := range p {
+		buf = appendLine(buf, '*', "2")
+		buf = pair.Key.AppendTo(buf, protover)
+		buf = pair.Value.AppendTo(buf, protover)
+	}
+	return buf
+}
+
+func (s Set) AppendTo(buf []byte, protover int) []byte {
+	if protover == RESP3 {
+		return appendAggregate(buf, '~', s, protover)
+	}
+	return appendAggregate(buf, '*', s, protover)
+}
+
+// FormatDouble formats a double the way Redis 7 does, with the shortest
+// representation that parses back to it, e.g. scores in ZSCAN replies
+func FormatDouble(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func (d Double) AppendTo(buf []byte, protover int) []byte {
+	text := FormatDouble(float64(d))
+	if protover == RESP3 {
+		return appendLine(buf, ',', text)
+	}
+	return appendBulk(buf, '$', text)
+}
+
+func (b Boolean) AppendTo(buf []byte, protover int) []byte {
+	if protover == RESP3 {
+		if b {
+			return appendLine(buf, '#', "t")
+		}
+		return appendLine(buf, '#', "f")
+	}
+	if b {
+		return appendLine(buf, ':', "1")
+	}
+	return appendLine(buf, ':', "0")
+}
+
+func (n BigNumber) AppendTo(buf []byte, protover int) []byte {
+	if protover == RESP3 {
+		return appendLine(buf, '(', string(n))
+	}
+	return appendBulk(buf, '$', string(n))
+}
+
+func (v Verbatim) AppendTo(buf []byte, protover int) []byte {
+	if protover == RESP3 {
+		return appendBulk(buf, '=', v.Format+":"+v.Text)
+	}
+	return appendBulk(buf, '$', v.Text)
+}
+
+func (p Push) AppendTo(buf []byte, protover int) []byte {
+	if protover == RESP3 {
+		return appendAggregate(buf, '>', p, protover)
+	}
+	return appendAggregate(buf, '*', p, protover)
+}
+
+func appendLine(buf []byte, prefix byte, s string) []byte {
+	buf = append(buf, prefix)
+	buf = append(buf, s...)
+	return append(buf, CRLF...)
+}
+
+func appendBulk(buf []byte, prefix byte, s string) []byte {
+	buf = appendLine(buf, prefix, strconv.Itoa(len(s)))
+	buf = append(buf, s...)
+	return append(buf, CRLF...)
+}
+
+func appendAggregate(buf []byte, prefix byte, entries []Reply, protover int) []byte {
+	buf = appendLine(buf, prefix, strconv.Itoa(len(entries)))
+	for _, entry := range entries {
+		buf = entry.AppendTo(buf, protover)
+	}
+	return buf
+}
+
+// OK is the +OK status reply
+var OK = SimpleString(RESPONSE_OK)
+
+// NewArray converts a nested []any of strings, integers and replies into an
+// Array, strings become bulk strings
+func NewArray(entries []any) Array {
+	array := make(Array, 0, len(entries))
+	for _, entry := range entries {
+		switch v := entry.(type) {
+		case Reply:
+			array = append(array, v)
+		case []any:
+			array = append(array, NewArray(v))
+		case string:
+			array = append(array, BulkString(v))
+		case int:
+			array = append(array, Integer(v))
+		case int64:
+			array = append(array, Integer(v))
+		}
+	}
+	return array
+}
+
+// NewStringArray builds an Array of bulk strings
+func NewStringArray(entries []string) Array {
+	array := make(Array, 0, len(entries))
+	for _, entry := range entries {
+		array = append(array, BulkString(entry))
+	}
+	return array
+}
@@ -1,83 +1,9 @@
 package protocol
 
 import (
-	"bufio"
-	"fmt"
-	"log"
 	"strconv"
-	"strings"
 )
 
-// ParseRequest parses a RESP array request from the reader
-func ParseRequest(reader *bufio.Reader) ([]string, error, int64) {
-	respRequest := []string{}
-	var numOfBytes int64
-	numOfBytes = 0
-	numOfLine, err := reader.ReadString('\n')
-	numOfBytes += int64(len(numOfLine))
-	if err != nil {
-		return RESP_ZERO_REQUEST, err, 0
-	}
-
-	numOfLine = strings.TrimSpace(numOfLine)
-	log.Println("numOfLine is: ", numOfLine)
-
-	// RESP format check
-	if !strings.HasPrefix(numOfLine, "*") {
-		log.Println("invalid RESP command")
-		return RESP_ZERO_REQUEST, nil, 0
-	}
-
-	lines, err := strconv.Atoi(numOfLine[1:])
-	if err != nil {
-		log.Printf("Error converting to int: %v, input was: %q", err, numOfLine[1:])
-		return RESP_ZERO_REQUEST, err, 0
-	}
-
-	for i := 0; i < lines; i++ {
-		// Read bulk string length line
-		l, err := reader.ReadString('\n')
-		numOfBytes += int64(len(l))
-		if err != nil {
-			return RESP_ZERO_REQUEST, err, 0
-		}
-
-		// Read bulk string data
-		command, err := reader.ReadString('\n')
-		numOfBytes += int64(len(command))
-		if err != nil {
-			return RESP_ZERO_REQUEST, err, 0
-		}
-
-		command = strings.TrimSpace(command)
-		respRequest = append(respRequest, command)
-	}
-	fmt.Println("PARSE REQUEST")
-
-	return respRequest, nil, numOfBytes
-}
-
-// BuildBulkString creates a RESP bulk string
-func BuildBulkString(s string) string {
-	if s == "" {
-		return "$-1" + CRLF
-	}
-	s = strings.TrimSpace(s)
-	strLen := len(s)
-	return "$" + strconv.Itoa(strLen) + CRLF + s + CRLF
-}
-
-// BuildSimpleString creates a RESP simple string
-func BuildSimpleString(s string) string {
-	s = strings.TrimSpace(s)
-	return "+" + s + CRLF
-}
-
-// BuildError creates a RESP error message
-func BuildError(msg string) string {
-	return "-ERR " + msg + CRLF
-}
-
 // BuildEmptyArray
 func BuildEmptyArray() string {
 	return "*0" + CRLF
@@ -111,6 +37,11 @@ func BuildArray(entries []any) string {
 	for _, entry := range entries {
 		switch v := entry.(type) {
 		case []any:
+			if len(v) == 0 {
+				// Only a top level empty array stands for "no result"
+				resp += BuildEmptyArray()
+				continue
+			}
 			res := BuildArray(v)
 			resp += res
 		case string:
@@ -118,26 +49,12 @@ func BuildArray(entries []any) string {
 			length := len(v)
 			resp += "$" + strconv.Itoa(length) + CRLF
 			resp += v + CRLF
+		case int:
+			resp += BuildInt(v)
+		case int64:
+			resp += BuildInteger(strconv.FormatInt(v, 10))
 		}
 	}
 
 	return resp
 }
-
-// BuildArrayFromResponses creates a RESP array from already-formatted RESP responses
-// This is specifically for transaction EXEC results where each element is already a complete RESP response
-func BuildArrayFromResponses(responses []string) string {
-	if len(responses) == 0 {
-		return BuildEmptyArray()
-	}
-
-	result := "*" + strconv.Itoa(len(responses)) + CRLF
-
-	for _, response := range responses {
-		// Each response is already a complete RESP-formatted string
-		// Just concatenate them directly
-		result += response
-	}
-
-	return result
-}
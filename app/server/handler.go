@@ -2,9 +2,11 @@ package server
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -18,58 +20,77 @@ import (
 // ConnectionHandler handles individual client connections
 type ConnectionHandler struct {
 	conn              net.Conn
-	cache             storage.Cache
+	databases         *storage.Databases
 	registry          *commands.CommandRegistry
 	reader            *bufio.Reader
+	writer            *bufio.Writer
 	transactionState  *commands.TransactionState
+	client            *commands.Client
 	metadata          *types.ServerMetadata // Metadata should have a list of active connections, it should get populated when PSYNC command is a success
 	isReplicationConn bool
 	connectionID      string
 }
 
 // NewConnectionHandler creates a new connection handler
-func NewConnectionHandler(conn net.Conn, cache storage.Cache, registry *commands.CommandRegistry, metadata *types.ServerMetadata) *ConnectionHandler {
+func NewConnectionHandler(conn net.Conn, databases *storage.Databases, registry *commands.CommandRegistry, metadata *types.ServerMetadata) *ConnectionHandler {
 	return &ConnectionHandler{
 		conn:              conn,
-		cache:             cache,
+		databases:         databases,
 		registry:          registry,
 		reader:            bufio.NewReader(conn),
+		writer:            bufio.NewWriter(conn),
 		transactionState:  commands.NewTransactionState(),
+		client:            commands.NewClient(),
 		metadata:          metadata,
 		isReplicationConn: false,
 		connectionID:      fmt.Sprintf("conn-%p", conn),
 	}
 }
 
-func (h *ConnectionHandler) IsGetAck(respRequest []string) bool {
-	if len(respRequest) != 3 {
-		return false
-	}
-	log.Printf("IsGetAck method: %+v", respRequest)
-	return strings.ToUpper(respRequest[0]) == "REPLCONF" && strings.ToUpper(respRequest[1]) == "GETACK"
-}
-
 // Handle processes commands from the client connection
 func (h *ConnectionHandler) Handle() {
 	// defer h.conn.Close()
 	defer func() {
 		log.Printf("Connection handler exiting for %s, isReplicationConn=%v",
 			h.conn.RemoteAddr(), h.isReplicationConn)
+		h.writer.Flush()
 		h.conn.Close()
+		h.client.Disconnect()
 	}()
 	fmt.Printf("New connection from %s\n", h.conn.RemoteAddr())
 
+	if h.isReplicationConn {
+		// This is our link to the master, it starts with the PSYNC reply
+		if err := h.consumeFullResync(); err != nil {
+			log.Printf("Error reading FULLRESYNC from master: %v", err)
+			return
+		}
+	}
+
 	for {
+		// Replies are buffered while pipelined requests are still waiting in
+		// the read buffer, so a whole batch is answered with a single write
+		if h.reader.Buffered() == 0 {
+			if err := h.writer.Flush(); err != nil {
+				log.Printf("Error writing response: %v", err)
+				break
+			}
+		}
+
 		log.Printf("Waiting for next command...") // Add this
 		// Parse RESP request
 		respRequest, err, n := protocol.ParseRequest(h.reader)
 		if err != nil {
+			var protocolErr *protocol.ProtocolError
+			if errors.As(err, &protocolErr) {
+				// The stream is out of sync, report the error and drop the client
+				h.writeReply(protocol.ErrorFromErr(protocolErr))
+			}
 			log.Printf("Connection closed or error parsing request: %v", err)
 			break
 		}
 
 		if len(respRequest) == 0 {
-			h.consumeEmptyRDB()
 			continue
 		}
 
@@ -77,7 +98,20 @@ func (h *ConnectionHandler) Handle() {
 
 		// Execute command
 		command := strings.ToUpper(respRequest[0])
+		stopWatching := func() {}
+		if h.registry.IsBlocking(respRequest) {
+			// Don't hold earlier pipelined replies back while this one waits
+			if err := h.writer.Flush(); err != nil {
+				log.Printf("Error writing response: %v", err)
+				break
+			}
+			stopWatching = h.watchDisconnect()
+		}
 		response := h.processCommand(command, respRequest)
+		stopWatching()
+		// The writes are replicated by now, clients blocked on their keys can
+		// be served
+		h.registry.BlockedClients().ServeReady(h.client, h.SendCommandsToReplicas)
 
 		// Handle REPLCONF ACK responses for WAIT commands
 		if command == "REPLCONF" && len(respRequest) == 3 && strings.ToUpper(respRequest[1]) == "ACK" {
@@ -95,12 +129,9 @@ func (h *ConnectionHandler) Handle() {
 			h.AddReplicasConnection()
 
 			// Send FULLRESYNC response first
-			for _, res := range response {
-				_, err = h.conn.Write([]byte(res))
-				if err != nil {
-					log.Printf("Error writing PSYNC response: %v", err)
-					break
-				}
+			if err := h.writeReply(response); err != nil {
+				log.Printf("Error writing PSYNC response: %v", err)
+				break
 			}
 
 			// Send raw RDB data
@@ -113,57 +144,102 @@ func (h *ConnectionHandler) Handle() {
 
 			// Send RDB with proper bulk string header + raw binary
 			rdbHeader := fmt.Sprintf("$%d\r\n", len(rdbData))
-			h.conn.Write([]byte(rdbHeader))
-			h.conn.Write(rdbData)
+			h.writer.WriteString(rdbHeader)
+			h.writer.Write(rdbData)
 
 			continue // Skip normal response processing
 		}
 
 		// Send response
-		for _, res := range response {
-			log.Printf("Response loop: isReplicationConn=%v, IsGetAck=%v, response=%s",
-				h.isReplicationConn, h.IsGetAck(respRequest), res)
-			shouldSendResponse := true
-
-			if h.isReplicationConn {
-				if h.IsGetAck(respRequest) {
-					// GETACK: always send response, don't count bytes
-					h.metadata.AddCommandProcessed(n)
-					shouldSendResponse = true
-				} else if strings.ToUpper(respRequest[0]) == "REPLCONF" {
-					// Other REPLCONF: send response, don't count bytes
-					shouldSendResponse = true
-					h.metadata.AddCommandProcessed(n)
-				} else {
-					// Data commands: count bytes, don't send response
-					h.metadata.AddCommandProcessed(n)
-					shouldSendResponse = false
-				}
-			}
-
-			if !shouldSendResponse {
+		if h.isReplicationConn {
+			// Every command from the master counts towards the processed
+			// offset, but only REPLCONF (e.g. GETACK) gets answered. The
+			// offset is bumped after execution so GETACK reports the bytes
+			// processed before it.
+			h.metadata.AddCommandProcessed(n)
+			if command != "REPLCONF" {
 				continue
 			}
-			_, err = h.conn.Write([]byte(res))
-			if err != nil {
-				log.Printf("Error writing response: %v", err)
-				break
-			}
+		}
+
+		if err := h.writeReply(response); err != nil {
+			log.Printf("Error writing response: %v", err)
+			break
 		}
 	}
 
 	fmt.Printf("Connection from %s closed\n", h.conn.RemoteAddr())
 }
 
+// watchDisconnect notices the client closing the connection while its
+// command is blocked, which unblocks it. The returned function stops watching
+// and must be called before reading the next request.
+func (h *ConnectionHandler) watchDisconnect() func() {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Peek returns as soon as the client sends anything, a pipelined
+		// request means the client is still there
+		if _, err := h.reader.Peek(1); err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+			h.client.Disconnect()
+		}
+	}()
+	return func() {
+		// An expired deadline interrupts the Peek, the error is consumed by it
+		h.conn.SetReadDeadline(time.Now())
+		<-done
+		h.conn.SetReadDeadline(time.Time{})
+	}
+}
+
 func (h *ConnectionHandler) AddReplicasConnection() {
 	h.metadata.AddReplicasConnection(h.conn)
 }
 
-func (h *ConnectionHandler) processCommand(cmdName string, args []string) []string {
+// writeReply encodes a reply with the protocol negotiated by the client,
+// a nil reply means the command has nothing to send back
+func (h *ConnectionHandler) writeReply(reply protocol.Reply) error {
+	if reply == nil {
+		return nil
+	}
+	_, err := h.writer.Write(protocol.Encode(reply, h.client.Protocol))
+	return err
+}
+
+func (h *ConnectionHandler) processCommand(cmdName string, args []string) protocol.Reply {
 	if cmdName == "REPLCONF" && !h.isReplicationConn {
 		h.isReplicationConn = true
 		log.Printf("Detected replication connection from %s", h.conn.RemoteAddr())
 	}
+
+	Command, err := h.registry.GetCommand(cmdName)
+	log.Printf("Command being processed is: %s", cmdName)
+	if err != nil {
+		h.transactionState.MarkAborted()
+		return protocol.ErrorFromErr(err)
+	}
+
+	info, _ := h.registry.GetCommandInfo(cmdName)
+	if err := info.CheckArity(args); err != nil {
+		h.transactionState.MarkAborted()
+		return protocol.ErrorFromErr(err)
+	}
+
+	// Replicas only accept writes coming from their master
+	if h.metadata.Role == "slave" && !h.isReplicationConn && info.Has(commands.FlagWrite) {
+		h.transactionState.MarkAborted()
+		return protocol.ErrReadOnly
+	}
+
+	// Commands that may grow the dataset first make room for themselves,
+	// replicas follow the evictions of their master instead
+	if h.metadata.Role == "master" && info.Has(commands.FlagDenyOOM) {
+		if err := h.databases.FreeMemoryIfNeeded(); err != nil {
+			h.transactionState.MarkAborted()
+			return protocol.ErrorFromErr(err)
+		}
+	}
+
 	switch cmdName {
 	case "MULTI":
 		return h.processMultiCommand()
@@ -173,100 +249,108 @@ func (h *ConnectionHandler) processCommand(cmdName string, args []string) []stri
 		return h.processDiscardCommand()
 	}
 
-	Command, err := h.registry.GetCommand(cmdName)
-	log.Printf("Command being processed is: %s", cmdName)
-	if err != nil {
-		return []string{protocol.BuildError("Invalid command")}
+	queueCommand := commands.QueueCommand{
+		Cmd:       Command,
+		Info:      info,
+		Args:      args,
+		Timestamp: time.Now().UnixNano(),
+		Metadata:  h.metadata,
+		Client:    h.client,
 	}
 
 	if h.transactionState.IsInTransaction() {
+		// A command that can't even be queued poisons the whole transaction
+		if err := Command.Validate(args); err != nil {
+			h.transactionState.MarkAborted()
+			return protocol.ErrorFromErr(err)
+		}
 		// QUEUE commands
-		err := h.transactionState.QueueCommand(Command, args)
+		err := h.transactionState.QueueCommand(&queueCommand)
 		if err != nil {
-			return []string{protocol.BuildError(err.Error())}
+			h.transactionState.MarkAborted()
+			return protocol.ErrorFromErr(err)
 		}
-		return []string{protocol.BuildSimpleString(protocol.RESPONSE_QUEUED)}
+		return protocol.SimpleString(protocol.RESPONSE_QUEUED)
 	}
 
-	// Otherwise execute them
-	queueCommand := commands.QueueCommand{
-		Cmd:       Command,
-		Args:      args,
-		Timestamp: time.Now().UnixNano(),
-		Metadata:  h.metadata,
-	}
-	// Here we can send the command to replica
-	h.SendCommandToReplicas(args)
-	return queueCommand.Execute(h.cache)
+	// Otherwise execute them, and let replicas know about the effects
+	db := h.client.DB
+	reply := queueCommand.Execute(h.databases.Get(db))
+	h.SendCommandsToReplicas(db, queueCommand.Propagation(reply))
+	return reply
 }
 
-func (h *ConnectionHandler) processMultiCommand() []string {
+func (h *ConnectionHandler) processMultiCommand() protocol.Reply {
 	if h.transactionState.IsInTransaction() {
-		return []string{protocol.BuildError(protocol.MULTI_IN_MULTI)}
+		return protocol.NewError(protocol.MULTI_IN_MULTI)
 	}
 
 	h.transactionState.StartTransaction()
-	// Send Command to Replicas from here
-	h.SendCommandToReplicas([]string{"MULTI"})
-	return []string{protocol.BuildSimpleString("OK")}
+	return protocol.OK
 }
 
-func (h *ConnectionHandler) processExecCommand() []string {
+func (h *ConnectionHandler) processExecCommand() protocol.Reply {
 	if !h.transactionState.IsInTransaction() {
-		return []string{protocol.BuildError(protocol.EXEC_BEFORE_MULTI)}
+		return protocol.NewError(protocol.EXEC_BEFORE_MULTI)
 	}
 
-	result := h.transactionState.ExecuteTransaction(h.cache)
-	res := make([]string, 0, len(result))
-	for _, val := range result {
-		res = append(res, val...)
+	if h.transactionState.IsAborted() {
+		h.transactionState.Reset()
+		return protocol.ErrExecAbort
 	}
+
+	db := h.client.DB
+	result, propagated := h.transactionState.ExecuteTransaction(h.databases, db)
 	h.transactionState.EndTransaction()
 
-	// Send the Command to Replicas from here
-	h.SendCommandToReplicas([]string{"EXEC"})
-	// Use the new function specifically designed for already-formatted RESP responses
-	return []string{protocol.BuildArrayFromResponses(res)}
+	// Replicas apply the writes of the transaction atomically as well
+	if len(propagated) > 0 {
+		batch := append([][]string{{"MULTI"}}, propagated...)
+		h.SendCommandsToReplicas(db, append(batch, []string{"EXEC"}))
+	}
+	return protocol.Array(result)
 }
 
-func (h *ConnectionHandler) processDiscardCommand() []string {
+func (h *ConnectionHandler) processDiscardCommand() protocol.Reply {
 	if !h.transactionState.IsInTransaction() {
-		return []string{protocol.BuildError(protocol.DISCARD_WITHOUT_MULTI)}
+		return protocol.NewError(protocol.DISCARD_WITHOUT_MULTI)
 	}
-	// Send to Replicas
 	h.transactionState.Reset()
-	h.SendCommandToReplicas([]string{"DISCARD"})
-	return []string{protocol.BuildSimpleString(protocol.RESPONSE_OK)}
+	return protocol.OK
 }
 
-func (h *ConnectionHandler) SendCommandToReplicas(Cmd []string) {
-	if h.metadata.Role == "master" && h.shouldReplicate(Cmd[0]) {
-		h.metadata.ReplChannel <- Cmd
+// SendCommandsToReplicas queues commands that ran against database db for
+// replication as a single batch
+func (h *ConnectionHandler) SendCommandsToReplicas(db int, cmds [][]string) {
+	if h.metadata.Role == "master" && len(cmds) > 0 {
+		h.metadata.ReplChannel <- types.ReplicationBatch{DB: db, Commands: cmds}
 	}
 }
 
-func (h *ConnectionHandler) shouldReplicate(cmdName string) bool {
-	writeCommands := map[string]bool{
-		"SET": true, "DEL": true, "INCR": true, "DECR": true,
-		"LPUSH": true, "RPUSH": true, "LPOP": true, "RPOP": true,
-		"XADD": true, "MULTI": true, "EXEC": true, "DISCARD": true,
+func (h *ConnectionHandler) consumeFullResync() error {
+	line, err := h.reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+FULLRESYNC") {
+		return fmt.Errorf("unexpected PSYNC reply: %q", line)
 	}
-	return writeCommands[cmdName]
-}
 
-func (h *ConnectionHandler) consumeEmptyRDB() {
-	// Read bytes until we find the start of next RESP command
-	for {
-		b, err := h.reader.ReadByte()
-		if err != nil {
-			log.Printf("Error consuming RDB: %v", err)
-			return
-		}
-		if b == '*' {
-			// Put the '*' back for the next ParseRequest
-			h.reader.UnreadByte()
-			break
-		}
+	header, err := h.reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	header = strings.TrimSpace(header)
+	if !strings.HasPrefix(header, "$") {
+		return fmt.Errorf("unexpected RDB header: %q", header)
+	}
+	rdbLength, err := strconv.Atoi(header[1:])
+	if err != nil || rdbLength < 0 {
+		return fmt.Errorf("invalid RDB length: %q", header)
+	}
+	if _, err := h.reader.Discard(rdbLength); err != nil {
+		return err
 	}
-	log.Printf("Consumed RDB file and found next command")
+	log.Printf("Consumed RDB file of %d bytes", rdbLength)
+	return nil
 }
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/app/commands"
+	"github.com/codecrafters-io/redis-starter-go/app/storage"
+	"github.com/codecrafters-io/redis-starter-go/app/types"
+)
+
+// countingConn counts the writes the handler issues on the connection
+type countingConn struct {
+	net.Conn
+	writes atomic.Int64
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	c.writes.Add(1)
+	return c.Conn.Write(p)
+}
+
+// BenchmarkPipeline sends batches of pipelined SETs and reads their replies.
+// Replies are flushed once the read buffer is drained, so writes/op stays at
+// one whatever the size of the batch, where flushing after every reply would
+// issue one write per command.
+func BenchmarkPipeline(b *testing.B) {
+	// The handler logs every request, which would dominate the measure
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer devNull.Close()
+	stdout := os.Stdout
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	for _, size := range []int{1, 16, 128} {
+		b.Run(fmt.Sprintf("batch=%d", size), func(b *testing.B) {
+			databases := storage.NewDatabases(16)
+			metadata := types.NewServerMetadata("master")
+			client, server := net.Pipe()
+			conn := &countingConn{Conn: server}
+			go NewConnectionHandler(conn, databases, commands.NewCommandRegistry(databases), metadata).Handle()
+			defer client.Close()
+
+			batch := strings.Repeat("*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n", size)
+			replies := bufio.NewReader(client)
+			b.ResetTimer()
+			for range b.N {
+				if _, err := io.WriteString(client, batch); err != nil {
+					b.Fatal(err)
+				}
+				for range size {
+					reply, err := replies.ReadString('\n')
+					if err != nil {
+						b.Fatal(err)
+					}
+					if reply != "+OK\r\n" {
+						b.Fatalf("unexpected reply %q", reply)
+					}
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(conn.writes.Load())/float64(b.N), "writes/op")
+			b.ReportMetric(float64(b.N*size)/b.Elapsed().Seconds(), "cmds/s")
+		})
+	}
+}
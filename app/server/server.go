@@ -13,17 +13,33 @@ import (
 // RedisServer represents the Redis server instance
 type RedisServer struct {
 	address        string
-	cache          storage.Cache
+	databases      *storage.Databases
 	registry       *commands.CommandRegistry
 	serverMetadata *types.ServerMetadata
 }
 
 // NewRedisServer creates a new Redis server instance
 func NewRedisServer(address string, metadata *types.ServerMetadata) *RedisServer {
+	databases := storage.NewDatabases(metadata.Databases)
+	databases.SetMaxMemory(metadata.MaxMemory, storage.EvictionPolicy(metadata.MaxMemoryPolicy))
+	if metadata.Role == "slave" {
+		// Replicas report expired keys and hash fields as missing but leave
+		// their removal to the DEL and HDEL sent by the master, so both sides
+		// stay consistent
+		databases.KeepExpiredKeys(true)
+	} else {
+		databases.OnKeyRemoved(func(db int, key string) {
+			metadata.ReplChannel <- types.ReplicationBatch{DB: db, Commands: [][]string{{"DEL", key}}}
+		})
+		databases.OnFieldsRemoved(func(db int, key string, fields []string) {
+			hdel := append([]string{"HDEL", key}, fields...)
+			metadata.ReplChannel <- types.ReplicationBatch{DB: db, Commands: [][]string{hdel}}
+		})
+	}
 	return &RedisServer{
 		address:        address,
-		cache:          storage.NewCache(),
-		registry:       commands.NewCommandRegistry(),
+		databases:      databases,
+		registry:       commands.NewCommandRegistry(databases),
 		serverMetadata: metadata,
 	}
 }
@@ -41,6 +57,10 @@ func (s *RedisServer) Start() {
 
 	fmt.Printf("Redis server listening on %s\n", s.address)
 
+	// Keys that expired and are never accessed again are removed in the
+	// background
+	go s.databases.RunActiveExpire(s.serverMetadata.ShutdownChannel)
+
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
@@ -49,13 +69,14 @@ func (s *RedisServer) Start() {
 		}
 
 		// Handle each connection in a separate goroutine
-		go NewConnectionHandler(conn, s.cache, s.registry, s.serverMetadata).Handle()
+		go NewConnectionHandler(conn, s.databases, s.registry, s.serverMetadata).Handle()
 	}
 }
 
 func (s *RedisServer) StartSlave(conn net.Conn) {
-	replicationHandler := NewConnectionHandler(conn, s.cache, s.registry, s.serverMetadata)
+	replicationHandler := NewConnectionHandler(conn, s.databases, s.registry, s.serverMetadata)
 	replicationHandler.isReplicationConn = true
+	replicationHandler.client.IsMaster = true
 	go replicationHandler.Handle()
 	s.Start()
 }
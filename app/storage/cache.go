@@ -6,78 +6,381 @@ import (
 	"sync"
 	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/app/glob"
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 )
 
 // Cache interface defines the operations for Redis storage
 type Cache interface {
 	Get(key string) (RedisValue, bool)
+	// Set stores value at key, discarding any expiration the key had
 	Set(key string, value RedisValue)
-	Delete(key string)
+	// SetWithExpiration stores value at key expiring at expiresAt, a zero
+	// expiresAt stores it without expiration
+	SetWithExpiration(key string, value RedisValue, expiresAt time.Time)
+	// Delete removes key, logically expired keys included, and reports
+	// whether a live key was removed
+	Delete(key string) bool
+	// Exists reports whether key exists without counting as an access
+	Exists(key string) bool
+	// MemoryUsage estimates the memory used by key, see EstimateSize. It
+	// doesn't count as an access, so inspecting a key doesn't change which
+	// keys get evicted.
+	MemoryUsage(key string, samples int) (int64, bool)
+	// Rename moves the value and the expiration of src to dst, overwriting
+	// dst unless nx is set. It reports false when nx prevented the rename
+	// and fails with ErrNoSuchKey when src doesn't exist.
+	Rename(src, dst string, nx bool) (bool, error)
+	// Copy stores a deep copy of the value and the expiration of src at
+	// dst, it reports false when src doesn't exist or dst exists and
+	// replace isn't set
+	Copy(src, dst string, replace bool) bool
+	// RandomKey returns a random live key
+	RandomKey() (string, bool)
 	Type(key string) string
-	CleanupExpired()
+	// ActiveExpire samples up to count volatile keys and deletes the expired
+	// ones
+	ActiveExpire(count int) (sampled, expired int)
+	// ExpiredKeys returns the number of keys that expired so far
+	ExpiredKeys() int64
+	// SetExpireHook registers a function called with every key removed
+	// because it expired
+	SetExpireHook(hook func(key string))
+	// KeepExpiredKeys makes expired keys read as missing without removing
+	// them, they stay until deleted explicitly
+	KeepExpiredKeys(keep bool)
+	// Size returns the number of keys
+	Size() int
+	// Flush removes every key
+	Flush()
+	// SetExpiration makes an existing key expire at expiresAt, it reports
+	// false when the key doesn't exist
+	SetExpiration(key string, expiresAt time.Time) bool
+	// GetExpiration returns when key expires, false when it has no expiration
+	GetExpiration(key string) (time.Time, bool)
+	// Persist removes the expiration of key, it reports whether there was one
+	Persist(key string) bool
+	// UsedMemory returns the estimated memory used by the keys
+	UsedMemory() int64
+	// MemoryStats breaks the used memory down
+	MemoryStats() MemoryStats
+	// ActiveExpireFields samples up to count hashes with volatile fields and
+	// deletes their expired fields
+	ActiveExpireFields(count int) (sampled, expired int)
+	// ExpiredFields returns the number of hash fields that expired so far
+	ExpiredFields() int64
+	// SetFieldExpireHook registers a function called with the hash fields
+	// removed because they expired
+	SetFieldExpireHook(hook func(key string, fields []string))
+	// RefreshSize updates the bookkeeping of key after its value changed in
+	// place: its estimated size and the expired fields of a hash
+	RefreshSize(key string)
+	// SampleKeys returns up to count random keys with their eviction
+	// attributes, only volatile keys when volatileOnly is set
+	SampleKeys(count int, volatileOnly bool) []KeySample
+	// Keys returns the keys matching the glob-style pattern
+	Keys(pattern string) []string
+	// Scan iterates the keyspace a few buckets at a time, the returned cursor
+	// is 0 once every key has been visited
+	Scan(cursor uint64, count int) (uint64, []string)
 	// Thread-safe stream operations
 	AddToStream(key string, entry *StreamEntry) (string, error)
 }
 
+// KeySample describes a key considered for eviction
+type KeySample struct {
+	Key       string
+	Idle      time.Duration
+	Frequency uint32
+	ExpiresAt time.Time
+}
+
 // InMemoryCache implements Cache interface with thread-safe operations
 type InMemoryCache struct {
-	data map[string]RedisValue
-	mu   sync.RWMutex
+	data *Dict[*keyEntry]
+	// expires holds the expiration time of the volatile keys, whatever the
+	// type of their value
+	expires map[string]time.Time
+	// expiredKeys counts the keys removed because they expired
+	expiredKeys int64
+	expireHook  func(key string)
+	keepExpired bool
+	// usedMemory is the sum of the estimated sizes of the keys
+	usedMemory int64
+	// field expiration state, see field_expire.go
+	volatileHashes  map[string]struct{}
+	expiredFields   int64
+	fieldExpireHook func(key string, fields []string)
+	mu              sync.RWMutex
 }
 
 // NewCache creates a new in-memory cache instance
 func NewCache() Cache {
 	return &InMemoryCache{
-		data: make(map[string]RedisValue),
+		data:           NewDict[*keyEntry](),
+		expires:        make(map[string]time.Time),
+		volatileHashes: make(map[string]struct{}),
+	}
+}
+
+// isExpired must be called with the lock held. A hash whose fields all
+// expired counts as expired too, like in Redis it stops existing.
+func (c *InMemoryCache) isExpired(key string, currentTime time.Time) bool {
+	expiresAt, volatile := c.expires[key]
+	if volatile && !currentTime.Before(expiresAt) {
+		return true
+	}
+	return c.fieldsExpired(key, currentTime)
+}
+
+// setExpire must be called with the write lock held
+func (c *InMemoryCache) setExpire(key string, expiresAt time.Time) {
+	if _, volatile := c.expires[key]; !volatile {
+		c.usedMemory += expireOverhead
+	}
+	c.expires[key] = expiresAt
+}
+
+// removeExpire must be called with the write lock held
+func (c *InMemoryCache) removeExpire(key string) {
+	if _, volatile := c.expires[key]; volatile {
+		c.usedMemory -= expireOverhead
+		delete(c.expires, key)
 	}
 }
 
+// deleteKey must be called with the write lock held
+func (c *InMemoryCache) deleteKey(key string) {
+	if e, exists := c.data.Get(key); exists {
+		c.usedMemory -= e.size
+	}
+	c.data.Delete(key)
+	c.removeExpire(key)
+	delete(c.volatileHashes, key)
+}
+
+// expireKey must be called with the write lock held
+func (c *InMemoryCache) expireKey(key string) {
+	c.deleteKey(key)
+	c.expiredKeys++
+}
+
 // Get retrieves a value from the cache, handling expiration
 func (c *InMemoryCache) Get(key string) (RedisValue, bool) {
+	now := time.Now()
 	c.mu.RLock()
-	value, exists := c.data[key]
+	e, exists := c.data.Get(key)
+	expired := exists && c.isExpired(key, now)
+	c.mu.RUnlock()
+
 	if !exists {
-		c.mu.RUnlock()
 		return nil, false
 	}
 
-	// Check if expired
-	if value.IsExpired(time.Now()) {
-		c.mu.RUnlock()
+	if expired {
 		// Upgrade to write lock to delete expired key
 		c.mu.Lock()
-		// Double-check after acquiring write lock
-		if val2, stillExists := c.data[key]; stillExists {
-			if val2.IsExpired(time.Now()) {
-				delete(c.data, key)
-				c.mu.Unlock()
+		// Double-check after acquiring write lock, the key may have been
+		// overwritten in between
+		if !c.isExpired(key, time.Now()) {
+			e, exists = c.data.Get(key)
+			c.mu.Unlock()
+			if !exists {
 				return nil, false
-			} else if stillExists {
-				c.mu.Unlock()
-				return val2, true
 			}
+			e.touch(now)
+			return e.value, true
 		}
+		if c.keepExpired {
+			c.mu.Unlock()
+			return nil, false
+		}
+		c.expireKey(key)
+		hook := c.expireHook
 		c.mu.Unlock()
-		return value, false
+
+		// The hook runs without the lock so it may use the cache
+		if hook != nil {
+			hook(key)
+		}
+		return nil, false
 	}
 
-	c.mu.RUnlock()
-	return value, true
+	e.touch(now)
+	return e.value, true
 }
 
 // Set stores a value in the cache
 func (c *InMemoryCache) Set(key string, value RedisValue) {
+	c.SetWithExpiration(key, value, time.Time{})
+}
+
+// SetWithExpiration stores a value in the cache along with its expiration
+func (c *InMemoryCache) SetWithExpiration(key string, value RedisValue, expiresAt time.Time) {
+	value = stored(value)
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.data[key] = value
+	if old, exists := c.data.Get(key); exists {
+		c.usedMemory -= old.size
+	}
+	e := newKeyEntry(key, value, time.Now())
+	c.data.Set(key, e)
+	c.usedMemory += e.size
+	c.trackFields(key, value)
+	if expiresAt.IsZero() {
+		c.removeExpire(key)
+	} else {
+		c.setExpire(key, expiresAt)
+	}
 }
 
-// Delete removes a key from the cache
-func (c *InMemoryCache) Delete(key string) {
+// Delete removes a key from the cache. Expired keys are removed as well
+// since replicas keep them until their master deletes them.
+//
+// The keyspace only drops its reference to the value, the garbage collector
+// reclaims the memory concurrently. This is what the lazy freeing of Redis
+// does with a background thread, so large values never stall a deletion.
+func (c *InMemoryCache) Delete(key string) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	delete(c.data, key)
+	_, exists := c.data.Get(key)
+	live := exists && !c.isExpired(key, time.Now())
+	c.deleteKey(key)
+	return live
+}
+
+// Exists reports whether key exists, without updating its access time
+func (c *InMemoryCache) Exists(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, exists := c.data.Get(key)
+	return exists && !c.isExpired(key, time.Now())
+}
+
+// MemoryUsage estimates the memory used by key without updating its access
+// time or frequency, an expired key reads as missing but isn't removed. The
+// value is measured under the read lock since values such as streams are
+// only guarded by the write lock of the cache.
+func (c *InMemoryCache) MemoryUsage(key string, samples int) (int64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, exists := c.data.Get(key)
+	if !exists || c.isExpired(key, time.Now()) {
+		return 0, false
+	}
+	return EstimateSize(key, e.value, samples), true
+}
+
+// lookupForWrite returns the entry of key for a command about to modify the
+// keyspace, removing the key first when it expired. Expired keys are found
+// while they are kept though, the writes of a replica come from its master
+// which decides what exists. It must be called with the write lock held, the
+// removed keys are appended to expired for the caller to pass them to the
+// expire hook once it unlocked.
+func (c *InMemoryCache) lookupForWrite(key string, now time.Time, expired *[]string) (*keyEntry, bool) {
+	e, exists := c.data.Get(key)
+	if !exists || c.keepExpired || !c.isExpired(key, now) {
+		return e, exists
+	}
+	c.expireKey(key)
+	*expired = append(*expired, key)
+	return nil, false
+}
+
+// runExpireHook must be called without the lock
+func runExpireHook(hook func(key string), keys []string) {
+	if hook == nil {
+		return
+	}
+	for _, key := range keys {
+		hook(key)
+	}
+}
+
+// Rename moves src to dst atomically, the entry keeps its access history
+func (c *InMemoryCache) Rename(src, dst string, nx bool) (bool, error) {
+	now := time.Now()
+	expired := make([]string, 0)
+	c.mu.Lock()
+	e, exists := c.lookupForWrite(src, now, &expired)
+	_, dstExists := c.lookupForWrite(dst, now, &expired)
+	hook := c.expireHook
+	renamed, err := c.rename(src, dst, e, exists, dstExists, nx)
+	c.mu.Unlock()
+
+	runExpireHook(hook, expired)
+	return renamed, err
+}
+
+// rename must be called with the write lock held
+func (c *InMemoryCache) rename(src, dst string, e *keyEntry, exists, dstExists, nx bool) (bool, error) {
+	if !exists {
+		return false, protocol.ErrNoSuchKey
+	}
+	if src == dst {
+		return !nx, nil
+	}
+	if nx && dstExists {
+		return false, nil
+	}
+	expiresAt, volatile := c.expires[src]
+	c.deleteKey(dst)
+	c.deleteKey(src)
+
+	// The size depends on the key name
+	e.size = EstimateSize(dst, e.value, bookkeepingSamples)
+	c.data.Set(dst, e)
+	c.usedMemory += e.size
+	c.trackFields(dst, e.value)
+	if volatile {
+		c.setExpire(dst, expiresAt)
+	}
+	return true, nil
+}
+
+// Copy duplicates src into dst atomically
+func (c *InMemoryCache) Copy(src, dst string, replace bool) bool {
+	now := time.Now()
+	expired := make([]string, 0)
+	c.mu.Lock()
+	e, exists := c.lookupForWrite(src, now, &expired)
+	_, dstExists := c.lookupForWrite(dst, now, &expired)
+	hook := c.expireHook
+	copied := exists && (replace || !dstExists)
+	if copied {
+		expiresAt := c.expires[src]
+		c.deleteKey(dst)
+		entry := newKeyEntry(dst, e.value.Copy(), now)
+		c.data.Set(dst, entry)
+		c.usedMemory += entry.size
+		c.trackFields(dst, entry.value)
+		if !expiresAt.IsZero() {
+			c.setExpire(dst, expiresAt)
+		}
+	}
+	c.mu.Unlock()
+
+	runExpireHook(hook, expired)
+	return copied
+}
+
+// RandomKey samples random keys until it finds one that didn't expire. Like
+// Redis it gives up after a bounded number of tries when nearly every key
+// expired, rather than walking the whole keyspace.
+func (c *InMemoryCache) RandomKey() (string, bool) {
+	now := time.Now()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	const maxTries = 100
+	key, found := "", false
+	for try := 0; try < maxTries && !found && c.data.Len() > 0; try++ {
+		c.data.Sample(1, func(sampled string, e *keyEntry) {
+			key = sampled
+			found = !c.isExpired(sampled, now)
+		})
+	}
+	return key, found
 }
 
 // Type returns the type of the value stored at key
@@ -85,63 +388,273 @@ func (c *InMemoryCache) Type(key string) string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	value, exists := c.data[key]
+	e, exists := c.data.Get(key)
 	if !exists {
 		return "none"
 	}
 
 	// Check if expired
-	if value.IsExpired(time.Now()) {
+	if c.isExpired(key, time.Now()) {
 		return "none"
 	}
 
-	return value.Type()
+	return e.value.Type()
 }
 
-// CleanupExpired removes all expired keys from the cache
-func (c *InMemoryCache) CleanupExpired() {
+// ActiveExpire checks a sample of the volatile keys, relying on the
+// randomized map iteration order to pick a different sample each call
+func (c *InMemoryCache) ActiveExpire(count int) (sampled, expired int) {
 	currentTime := time.Now()
+	expiredKeys := make([]string, 0)
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	for key, value := range c.data {
-		if value.IsExpired(currentTime) {
-			delete(c.data, key)
+	if c.keepExpired {
+		c.mu.Unlock()
+		return 0, 0
+	}
+	for key := range c.expires {
+		if sampled == count {
+			break
+		}
+		sampled++
+		if c.isExpired(key, currentTime) {
+			c.expireKey(key)
+			expiredKeys = append(expiredKeys, key)
 		}
 	}
+	hook := c.expireHook
+	c.mu.Unlock()
+
+	runExpireHook(hook, expiredKeys)
+	return sampled, len(expiredKeys)
 }
 
-// AddToStream atomically adds an entry to a stream
-func (c *InMemoryCache) AddToStream(key string, entry *StreamEntry) (string, error) {
+// SetExpireHook registers the function called with every expired key
+func (c *InMemoryCache) SetExpireHook(hook func(key string)) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.expireHook = hook
+}
 
-	value, exists := c.data[key]
-	var newEntryID *EntryID
-	var err error
-	if exists {
-		// Check if it's actually a stream
-		if streamVal, ok := value.(*StreamValue); ok {
-			// Validate the new entry ID using the stream's validation method
-			newEntryID, err = streamVal.AddEntry(entry)
-			if err != nil {
-				return protocol.EMPTY_STRING, err
+// KeepExpiredKeys turns the removal of expired keys off or back on
+func (c *InMemoryCache) KeepExpiredKeys(keep bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keepExpired = keep
+}
+
+// ExpiredKeys returns the number of keys that expired, lazily or actively
+func (c *InMemoryCache) ExpiredKeys() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.expiredKeys
+}
+
+// Size returns the number of keys, including the expired ones that weren't
+// removed yet
+func (c *InMemoryCache) Size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.data.Len()
+}
+
+// Flush removes every key from the cache
+func (c *InMemoryCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = NewDict[*keyEntry]()
+	c.expires = make(map[string]time.Time)
+	c.volatileHashes = make(map[string]struct{})
+	c.usedMemory = 0
+}
+
+// UsedMemory returns the estimated memory used by the keys
+func (c *InMemoryCache) UsedMemory() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.usedMemory
+}
+
+// MemoryStats breaks the used memory down into data and bookkeeping
+func (c *InMemoryCache) MemoryStats() MemoryStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return MemoryStats{
+		Keys:            c.data.Len(),
+		VolatileKeys:    len(c.expires),
+		Used:            c.usedMemory,
+		MainOverhead:    int64(c.data.Len()) * keyOverhead,
+		ExpiresOverhead: int64(len(c.expires)) * expireOverhead,
+	}
+}
+
+// RefreshSize estimates the size of a key again. The expired fields of a
+// hash are removed at this point too, so writes delete them lazily.
+func (c *InMemoryCache) RefreshSize(key string) {
+	c.mu.Lock()
+	e, exists := c.data.Get(key)
+	if !exists {
+		c.mu.Unlock()
+		return
+	}
+	expired := c.expireFields(key, e, time.Now())
+	hook := c.fieldExpireHook
+	c.mu.Unlock()
+
+	if hook != nil && len(expired) > 0 {
+		hook(key, expired)
+	}
+}
+
+// refreshSize must be called with the write lock held
+func (c *InMemoryCache) refreshSize(key string, e *keyEntry) {
+	size := EstimateSize(key, e.value, bookkeepingSamples)
+	c.usedMemory += size - e.size
+	e.size = size
+}
+
+// SampleKeys picks random keys
+func (c *InMemoryCache) SampleKeys(count int, volatileOnly bool) []KeySample {
+	now := time.Now()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	samples := make([]KeySample, 0, count)
+	sample := func(key string, e *keyEntry) {
+		samples = append(samples, KeySample{
+			Key:       key,
+			Idle:      e.idle(now),
+			Frequency: e.frequency(now),
+			ExpiresAt: c.expires[key],
+		})
+	}
+
+	if volatileOnly {
+		// The randomized map iteration order picks the sample
+		for key := range c.expires {
+			if len(samples) == count {
+				break
+			}
+			if e, exists := c.data.Get(key); exists {
+				sample(key, e)
 			}
-		} else {
-			return protocol.EMPTY_STRING, errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
 		}
-	} else {
-		// Create new stream
-		log.Println("I'm inside the case where there is no entry for the streamKey")
-		streamVal := StreamValue{Entries: []StreamEntry{}}
-		c.data[key] = &streamVal
-		newEntryID, err = streamVal.AddEntry(entry)
-		if err != nil {
-			return protocol.EMPTY_STRING, errors.New(protocol.INVALID_ENTRY_ID)
+		return samples
+	}
+	c.data.Sample(count, sample)
+	return samples
+}
+
+// Keys returns the keys matching the glob-style pattern, the whole keyspace
+// is walked under the read lock
+func (c *InMemoryCache) Keys(pattern string) []string {
+	now := time.Now()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := make([]string, 0)
+	c.data.Range(func(key string, e *keyEntry) bool {
+		if !c.isExpired(key, now) && glob.Match(pattern, key) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	return keys
+}
+
+// Scan returns the keys of the next buckets of the keyspace starting at
+// cursor, along with the cursor to continue from. It visits buckets until it
+// gathered about count keys, or 10 times count buckets when they are sparse.
+func (c *InMemoryCache) Scan(cursor uint64, count int) (uint64, []string) {
+	now := time.Now()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := make([]string, 0, count)
+	collect := func(key string, e *keyEntry) {
+		if !c.isExpired(key, now) {
+			keys = append(keys, key)
+		}
+	}
+	for visited := 0; visited < count*10; visited++ {
+		cursor = c.data.Scan(cursor, collect)
+		if cursor == 0 || len(keys) >= count {
+			break
 		}
 	}
+	return cursor, keys
+}
+
+// SetExpiration sets the expiration time of an existing key
+func (c *InMemoryCache) SetExpiration(key string, expiresAt time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.data.Get(key); !exists || c.isExpired(key, time.Now()) {
+		return false
+	}
+	c.setExpire(key, expiresAt)
+	return true
+}
+
+// GetExpiration returns the expiration time of a key
+func (c *InMemoryCache) GetExpiration(key string) (time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	expiresAt, volatile := c.expires[key]
+	return expiresAt, volatile
+}
+
+// Persist turns a volatile key into a persistent one
+func (c *InMemoryCache) Persist(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, volatile := c.expires[key]; !volatile || c.isExpired(key, time.Now()) {
+		return false
+	}
+	c.removeExpire(key)
+	return true
+}
+
+// AddToStream atomically adds an entry to a stream, a stream that expired
+// is replaced by a new one
+func (c *InMemoryCache) AddToStream(key string, entry *StreamEntry) (string, error) {
+	now := time.Now()
+	expired := make([]string, 0)
+	c.mu.Lock()
+	existing, exists := c.lookupForWrite(key, now, &expired)
+	hook := c.expireHook
+	newEntryID, err := c.addToStream(key, entry, existing, exists, now)
+	c.mu.Unlock()
 
+	runExpireHook(hook, expired)
+	if err != nil {
+		return protocol.EMPTY_STRING, err
+	}
 	log.Println("value of the inserted entryId is: ", newEntryID)
 
 	return newEntryID.GetEntryID(), nil
 }
+
+// addToStream must be called with the write lock held
+func (c *InMemoryCache) addToStream(key string, entry *StreamEntry, existing *keyEntry, exists bool, now time.Time) (*EntryID, error) {
+	if exists {
+		// Check if it's actually a stream
+		streamVal, ok := existing.value.(*StreamValue)
+		if !ok {
+			return nil, protocol.ErrWrongType
+		}
+		// Validate the new entry ID using the stream's validation method
+		return streamVal.AddEntry(entry)
+	}
+
+	// Create new stream
+	log.Println("I'm inside the case where there is no entry for the streamKey")
+	streamVal := StreamValue{Entries: []StreamEntry{}}
+	newEntryID, err := streamVal.AddEntry(entry)
+	if err != nil {
+		return nil, errors.New(protocol.INVALID_ENTRY_ID)
+	}
+	e := newKeyEntry(key, &streamVal, now)
+	c.data.Set(key, e)
+	c.usedMemory += e.size
+	return newEntryID, nil
+}
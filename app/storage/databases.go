@@ -0,0 +1,173 @@
+package storage
+
+import "sync"
+
+// Databases is the fixed set of numbered keyspaces served by the server.
+// Connections address a database by index, SWAPDB exchanges the contents
+// behind two indexes for every connection at once.
+type Databases struct {
+	dbs []Cache
+	mu  sync.RWMutex
+
+	// onRemove is called with every key the server removed on its own
+	onRemove func(db int, key string)
+	// onFieldsRemove is called with the hash fields that expired
+	onFieldsRemove func(db int, key string, fields []string)
+
+	// eviction state, see eviction.go
+	maxMemory   int64
+	policy      EvictionPolicy
+	evictedKeys int64
+	evictionMu  sync.Mutex
+
+	// active expiration state, see expire_cycle.go
+	nextExpireDB int
+	expireStats  ExpireStats
+	statsMu      sync.Mutex
+}
+
+// NewDatabases creates count empty databases
+func NewDatabases(count int) *Databases {
+	dbs := make([]Cache, count)
+	for i := range dbs {
+		dbs[i] = NewCache()
+	}
+	return &Databases{dbs: dbs}
+}
+
+// OnKeyRemoved registers the function called with the database index and
+// the name of every key removed because it expired or was evicted. It must be
+// registered before the databases are used.
+func (d *Databases) OnKeyRemoved(hook func(db int, key string)) {
+	d.onRemove = hook
+	for i, db := range d.dbs {
+		d.setExpireHook(i, db)
+	}
+}
+
+// OnFieldsRemoved registers the function called with the database index, the
+// key and the fields of every hash whose fields expired. It must be
+// registered before the databases are used.
+func (d *Databases) OnFieldsRemoved(hook func(db int, key string, fields []string)) {
+	d.onFieldsRemove = hook
+	for i, db := range d.dbs {
+		d.setExpireHook(i, db)
+	}
+}
+
+// setExpireHook binds db to its index for the expire hooks
+func (d *Databases) setExpireHook(index int, db Cache) {
+	db.SetExpireHook(func(key string) {
+		if d.onRemove != nil {
+			d.onRemove(index, key)
+		}
+	})
+	db.SetFieldExpireHook(func(key string, fields []string) {
+		if d.onFieldsRemove != nil {
+			d.onFieldsRemove(index, key, fields)
+		}
+	})
+}
+
+// KeepExpiredKeys stops every database from removing expired keys on its
+// own, they are reported missing on reads until deleted explicitly. Replicas
+// work this way and wait for the DEL of their master.
+func (d *Databases) KeepExpiredKeys(keep bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, db := range d.dbs {
+		db.KeepExpiredKeys(keep)
+	}
+}
+
+// Count returns the number of databases
+func (d *Databases) Count() int {
+	return len(d.dbs)
+}
+
+// IsValid reports whether index addresses an existing database
+func (d *Databases) IsValid(index int) bool {
+	return index >= 0 && index < len(d.dbs)
+}
+
+// Get returns the database at index
+func (d *Databases) Get(index int) Cache {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.dbs[index]
+}
+
+// Swap exchanges the contents of two databases
+func (d *Databases) Swap(a, b int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dbs[a], d.dbs[b] = d.dbs[b], d.dbs[a]
+	// Keys expiring from now on belong to the other index
+	d.setExpireHook(a, d.dbs[a])
+	d.setExpireHook(b, d.dbs[b])
+}
+
+// views returns the databases src and dst, seen through IgnoringExpiry when
+// ignoreExpiry is set. It must be called with the lock held.
+func (d *Databases) views(src, dst int, ignoreExpiry bool) (Cache, Cache) {
+	if ignoreExpiry {
+		return IgnoringExpiry(d.dbs[src]), IgnoringExpiry(d.dbs[dst])
+	}
+	return d.dbs[src], d.dbs[dst]
+}
+
+// Move transfers key from the src database to dst. Nothing is moved when the
+// key doesn't exist in src or already exists in dst. The master of a replica
+// sets ignoreExpiry, whether a key expired is up to it.
+func (d *Databases) Move(key string, src, dst int, ignoreExpiry bool) bool {
+	// Holding the write lock keeps SWAPDB from shuffling the databases
+	// while the key is in flight
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	from, to := d.views(src, dst, ignoreExpiry)
+	value, exists := from.Get(key)
+	if !exists {
+		return false
+	}
+	if _, exists := to.Get(key); exists {
+		return false
+	}
+	// The key keeps its time to live in the destination database
+	expiresAt, _ := from.GetExpiration(key)
+	to.SetWithExpiration(key, value, expiresAt)
+	from.Delete(key)
+	return true
+}
+
+// Copy duplicates key of database src as dstKey in database dst, keeping its
+// time to live. It reports false when the key doesn't exist or dstKey exists
+// and replace isn't set. ignoreExpiry is the same as for Move.
+func (d *Databases) Copy(key, dstKey string, src, dst int, replace, ignoreExpiry bool) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	from, to := d.views(src, dst, ignoreExpiry)
+	if src == dst {
+		return from.Copy(key, dstKey, replace)
+	}
+	value, exists := from.Get(key)
+	if !exists {
+		return false
+	}
+	if !replace && to.Exists(dstKey) {
+		return false
+	}
+	expiresAt, _ := from.GetExpiration(key)
+	to.SetWithExpiration(dstKey, value.Copy(), expiresAt)
+	return true
+}
+
+// FlushAll removes every key of every database
+func (d *Databases) FlushAll() {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, db := range d.dbs {
+		db.Flush()
+	}
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"math/rand"
+)
+
+const dictMinBuckets = 4
+
+var dictSeed = maphash.MakeSeed()
+
+type dictEntry[V any] struct {
+	key   string
+	value V
+}
+
+// Dict is a string keyed hash table with a stable cursor. Unlike a Go map it
+// can be scanned a few buckets at a time: every key present for the whole
+// duration of a scan is returned at least once, even when keys are added or
+// removed and the table resizes between calls. Dict is not safe for
+// concurrent use.
+type Dict[V any] struct {
+	buckets [][]dictEntry[V]
+	size    int
+}
+
+// NewDict creates an empty Dict
+func NewDict[V any]() *Dict[V] {
+	return &Dict[V]{buckets: make([][]dictEntry[V], dictMinBuckets)}
+}
+
+func (d *Dict[V]) bucketIndex(key string) uint64 {
+	return maphash.String(dictSeed, key) & uint64(len(d.buckets)-1)
+}
+
+// Len returns the number of keys
+func (d *Dict[V]) Len() int {
+	return d.size
+}
+
+// Get returns the value stored at key
+func (d *Dict[V]) Get(key string) (V, bool) {
+	for _, e := range d.buckets[d.bucketIndex(key)] {
+		if e.key == key {
+			return e.value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// Set stores value at key, it reports whether the key is new
+func (d *Dict[V]) Set(key string, value V) bool {
+	index := d.bucketIndex(key)
+	for i, e := range d.buckets[index] {
+		if e.key == key {
+			d.buckets[index][i].value = value
+			return false
+		}
+	}
+	d.buckets[index] = append(d.buckets[index], dictEntry[V]{key: key, value: value})
+	d.size++
+	if d.size > len(d.buckets) {
+		d.resize(len(d.buckets) * 2)
+	}
+	return true
+}
+
+// Delete removes key, it reports whether it was present
+func (d *Dict[V]) Delete(key string) bool {
+	index := d.bucketIndex(key)
+	bucket := d.buckets[index]
+	for i, e := range bucket {
+		if e.key == key {
+			bucket[i] = bucket[len(bucket)-1]
+			bucket[len(bucket)-1] = dictEntry[V]{}
+			d.buckets[index] = bucket[:len(bucket)-1]
+			d.size--
+			if len(d.buckets) > dictMinBuckets && d.size < len(d.buckets)/8 {
+				d.resize(len(d.buckets) / 2)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dict[V]) resize(buckets int) {
+	old := d.buckets
+	d.buckets = make([][]dictEntry[V], buckets)
+	for _, bucket := range old {
+		for _, e := range bucket {
+			index := d.bucketIndex(e.key)
+			d.buckets[index] = append(d.buckets[index], e)
+		}
+	}
+}
+
+// Range calls fn for every key until it returns false
+func (d *Dict[V]) Range(fn func(key string, value V) bool) {
+	for _, bucket := range d.buckets {
+		for _, e := range bucket {
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// Scan calls fn for the keys of the bucket addressed by cursor and returns
+// the cursor of the next bucket, 0 once the whole table has been visited.
+//
+// The cursor is incremented on its reversed bits, so it walks the high bits
+// of the bucket index first. When the table doubles, bucket i splits into i
+// and i+size, both of which come after the buckets already visited in this
+// order, and when it halves the buckets merge into ones not visited yet
+// either. Keys may be returned more than once, but never skipped.
+func (d *Dict[V]) Scan(cursor uint64, fn func(key string, value V)) uint64 {
+	mask := uint64(len(d.buckets) - 1)
+	for _, e := range d.buckets[cursor&mask] {
+		fn(e.key, e.value)
+	}
+
+	cursor |= ^mask
+	cursor = bits.Reverse64(cursor)
+	cursor++
+	return bits.Reverse64(cursor)
+}
+
+// Random returns a random key along with its value. Like Redis it picks a
+// random non-empty bucket and a random key in it, which is close to uniform
+// since buckets hold about one key each.
+func (d *Dict[V]) Random() (string, V, bool) {
+	if d.size == 0 {
+		var zero V
+		return "", zero, false
+	}
+	for {
+		bucket := d.buckets[rand.Intn(len(d.buckets))]
+		if len(bucket) > 0 {
+			e := bucket[rand.Intn(len(bucket))]
+			return e.key, e.value, true
+		}
+	}
+}
+
+// Sample calls fn for up to count keys, starting at a random bucket
+func (d *Dict[V]) Sample(count int, fn func(key string, value V)) {
+	if d.size == 0 || count <= 0 {
+		return
+	}
+	start := rand.Intn(len(d.buckets))
+	sampled := 0
+	for i := 0; i < len(d.buckets) && sampled < count; i++ {
+		for _, e := range d.buckets[(start+i)%len(d.buckets)] {
+			fn(e.key, e.value)
+			sampled++
+			if sampled == count {
+				return
+			}
+		}
+	}
+}
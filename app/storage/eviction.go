@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// EvictionPolicy selects which keys are evicted once maxmemory is reached
+type EvictionPolicy string
+
+const (
+	NoEviction     EvictionPolicy = "noeviction"
+	AllKeysLRU     EvictionPolicy = "allkeys-lru"
+	VolatileLRU    EvictionPolicy = "volatile-lru"
+	AllKeysLFU     EvictionPolicy = "allkeys-lfu"
+	VolatileLFU    EvictionPolicy = "volatile-lfu"
+	AllKeysRandom  EvictionPolicy = "allkeys-random"
+	VolatileRandom EvictionPolicy = "volatile-random"
+	VolatileTTL    EvictionPolicy = "volatile-ttl"
+)
+
+// MaxMemorySamples is how many keys of each database are sampled to pick an
+// eviction victim, more samples approximate the policy better but cost more
+const MaxMemorySamples = 5
+
+// ParseEvictionPolicy validates a maxmemory-policy value
+func ParseEvictionPolicy(name string) (EvictionPolicy, error) {
+	switch policy := EvictionPolicy(name); policy {
+	case NoEviction, AllKeysLRU, VolatileLRU, AllKeysLFU, VolatileLFU,
+		AllKeysRandom, VolatileRandom, VolatileTTL:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid maxmemory-policy '%s'", name)
+}
+
+// volatileOnly reports whether the policy only evicts keys with a TTL
+func (p EvictionPolicy) volatileOnly() bool {
+	switch p {
+	case VolatileLRU, VolatileLFU, VolatileRandom, VolatileTTL:
+		return true
+	}
+	return false
+}
+
+// better reports whether candidate should be evicted before current
+func (p EvictionPolicy) better(candidate, current KeySample) bool {
+	switch p {
+	case AllKeysLRU, VolatileLRU:
+		return candidate.Idle > current.Idle
+	case AllKeysLFU, VolatileLFU:
+		return candidate.Frequency < current.Frequency
+	case VolatileTTL:
+		return candidate.ExpiresAt.Before(current.ExpiresAt)
+	}
+	// Random policies keep the first sample, which is random already
+	return false
+}
+
+// SetMaxMemory configures the memory limit in bytes, 0 means unlimited
+func (d *Databases) SetMaxMemory(limit int64, policy EvictionPolicy) {
+	d.evictionMu.Lock()
+	defer d.evictionMu.Unlock()
+	d.maxMemory = limit
+	d.policy = policy
+}
+
+// UsedMemory returns the estimated memory used by all databases
+func (d *Databases) UsedMemory() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var total int64
+	for _, db := range d.dbs {
+		total += db.UsedMemory()
+	}
+	return total
+}
+
+// EvictedKeys returns the number of keys evicted because of maxmemory
+func (d *Databases) EvictedKeys() int64 {
+	d.evictionMu.Lock()
+	defer d.evictionMu.Unlock()
+	return d.evictedKeys
+}
+
+// FreeMemoryIfNeeded evicts keys according to the policy until the used
+// memory fits maxmemory again. It fails with an OOM error when the limit
+// can't be honoured, so the command about to use more memory is refused.
+func (d *Databases) FreeMemoryIfNeeded() error {
+	d.evictionMu.Lock()
+	defer d.evictionMu.Unlock()
+
+	if d.maxMemory == 0 {
+		return nil
+	}
+	for d.UsedMemory() > d.maxMemory {
+		if d.policy == NoEviction {
+			return protocol.ErrOOM
+		}
+		index, key, found := d.evictionCandidate()
+		if !found {
+			return protocol.ErrOOM
+		}
+		d.Get(index).Delete(key)
+		d.evictedKeys++
+		if d.onRemove != nil {
+			d.onRemove(index, key)
+		}
+	}
+	return nil
+}
+
+// evictionCandidate samples every database and returns the best key to
+// evict according to the policy
+func (d *Databases) evictionCandidate() (int, string, bool) {
+	var best KeySample
+	bestDB, found := 0, false
+	now := time.Now()
+	for index := 0; index < d.Count(); index++ {
+		for _, sample := range d.Get(index).SampleKeys(MaxMemorySamples, d.policy.volatileOnly()) {
+			// Keys that expired already are the cheapest victims
+			if !sample.ExpiresAt.IsZero() && !sample.ExpiresAt.After(now) {
+				return index, sample.Key, true
+			}
+			if !found || d.policy.better(sample, best) {
+				best, bestDB, found = sample, index, true
+			}
+		}
+	}
+	return bestDB, best.Key, found
+}
@@ -0,0 +1,127 @@
+package storage
+
+import "time"
+
+// Active expiration tunables, matching the defaults of Redis
+const (
+	// ActiveExpireCycleHz is how many cycles run per second
+	ActiveExpireCycleHz = 10
+	// ActiveExpireCycleKeysPerLoop is how many volatile keys are sampled per
+	// database in each iteration
+	ActiveExpireCycleKeysPerLoop = 20
+	// ActiveExpireCycleAcceptableStale is the percentage of expired keys in
+	// a sample below which a database is considered clean enough
+	ActiveExpireCycleAcceptableStale = 10
+	// ActiveExpireCycleTimePerc is the percentage of the cycle period a
+	// single cycle may spend expiring keys
+	ActiveExpireCycleTimePerc = 25
+)
+
+// ExpireStats describes how the active expiration is doing
+type ExpireStats struct {
+	// StalePerc estimates the percentage of the volatile keys that are
+	// expired but not removed yet
+	StalePerc float64
+	// TimeCapReached counts the cycles that stopped because they ran out of
+	// time
+	TimeCapReached int64
+}
+
+// RunActiveExpire runs ActiveExpireCycle ActiveExpireCycleHz times per second
+// until stop is closed
+func (d *Databases) RunActiveExpire(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Second / ActiveExpireCycleHz)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.ActiveExpireCycle()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// ActiveExpireCycle removes expired keys that nobody accesses anymore. Each
+// database is sampled repeatedly while the ratio of expired keys in the
+// sample stays above ActiveExpireCycleAcceptableStale, as long as the cycle
+// stays within its time budget. Databases not reached before the budget ran
+// out are the first ones visited by the next cycle.
+func (d *Databases) ActiveExpireCycle() {
+	start := time.Now()
+	budget := time.Second / ActiveExpireCycleHz * ActiveExpireCycleTimePerc / 100
+	totalSampled, totalExpired := 0, 0
+	timedOut := false
+
+	for visited := 0; visited < d.Count() && !timedOut; visited++ {
+		index := d.nextExpireDB
+		d.nextExpireDB = (d.nextExpireDB + 1) % d.Count()
+		db := d.Get(index)
+
+		for {
+			sampled, expired := db.ActiveExpire(ActiveExpireCycleKeysPerLoop)
+			totalSampled += sampled
+			totalExpired += expired
+			if sampled == 0 || expired*100/sampled <= ActiveExpireCycleAcceptableStale {
+				break
+			}
+			if time.Since(start) > budget {
+				timedOut = true
+				break
+			}
+		}
+		// Hashes with expired fields are visited until a sample comes back
+		// clean, they don't count towards the stale keys estimate
+		for !timedOut {
+			sampled, expired := db.ActiveExpireFields(ActiveExpireCycleKeysPerLoop)
+			if sampled < ActiveExpireCycleKeysPerLoop || expired == 0 {
+				break
+			}
+			if time.Since(start) > budget {
+				timedOut = true
+			}
+		}
+	}
+
+	d.statsMu.Lock()
+	defer d.statsMu.Unlock()
+	if timedOut {
+		d.expireStats.TimeCapReached++
+	}
+	// Smooth the estimate over cycles, a single sample is too noisy
+	currentPerc := 0.0
+	if totalSampled > 0 {
+		currentPerc = float64(totalExpired) * 100 / float64(totalSampled)
+	}
+	d.expireStats.StalePerc = currentPerc*0.05 + d.expireStats.StalePerc*0.95
+}
+
+// ExpireStats returns the statistics of the active expiration
+func (d *Databases) ExpireStats() ExpireStats {
+	d.statsMu.Lock()
+	defer d.statsMu.Unlock()
+	return d.expireStats
+}
+
+// ExpiredFields returns the number of hash fields that expired in all
+// databases
+func (d *Databases) ExpiredFields() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var total int64
+	for _, db := range d.dbs {
+		total += db.ExpiredFields()
+	}
+	return total
+}
+
+// ExpiredKeys returns the number of keys that expired in all databases
+func (d *Databases) ExpiredKeys() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var total int64
+	for _, db := range d.dbs {
+		total += db.ExpiredKeys()
+	}
+	return total
+}
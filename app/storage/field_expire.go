@@ -0,0 +1,105 @@
+package storage
+
+import "time"
+
+// trackFields records whether value is a hash with volatile fields, which
+// the active expiration has to visit. It must be called with the write lock
+// held whenever the value stored at key changes.
+func (c *InMemoryCache) trackFields(key string, value RedisValue) {
+	if hash, ok := value.(*HashValue); ok && hash.HasVolatileFields() {
+		c.volatileHashes[key] = struct{}{}
+		return
+	}
+	delete(c.volatileHashes, key)
+}
+
+// fieldsExpired reports whether key holds a hash with volatile fields that
+// all expired. It must be called with the lock held.
+func (c *InMemoryCache) fieldsExpired(key string, now time.Time) bool {
+	if _, volatile := c.volatileHashes[key]; !volatile {
+		return false
+	}
+	e, exists := c.data.Get(key)
+	if !exists {
+		return false
+	}
+	hash, ok := e.value.(*HashValue)
+	if !ok {
+		return false
+	}
+	hash.mu.RLock()
+	defer hash.mu.RUnlock()
+	return hash.len(now) == 0
+}
+
+// expireFields removes the expired fields of the hash stored at key, along
+// with the key once no field is left, and refreshes the size of what
+// remains. It must be called with the write lock held, the caller passes the
+// returned fields to the field expire hook once it unlocked.
+func (c *InMemoryCache) expireFields(key string, e *keyEntry, now time.Time) []string {
+	hash, ok := e.value.(*HashValue)
+	var expired []string
+	if ok && !c.keepExpired {
+		expired = hash.ExpireFields(now)
+		c.expiredFields += int64(len(expired))
+	}
+	if ok && len(expired) > 0 && hash.Len() == 0 {
+		c.deleteKey(key)
+		return expired
+	}
+	c.trackFields(key, e.value)
+	c.refreshSize(key, e)
+	return expired
+}
+
+// ActiveExpireFields checks a sample of the hashes with volatile fields,
+// relying on the randomized map iteration order to pick a different sample
+// each call
+func (c *InMemoryCache) ActiveExpireFields(count int) (sampled, expired int) {
+	now := time.Now()
+	removed := make(map[string][]string)
+	c.mu.Lock()
+	if c.keepExpired {
+		c.mu.Unlock()
+		return 0, 0
+	}
+	for key := range c.volatileHashes {
+		if sampled == count {
+			break
+		}
+		sampled++
+		e, exists := c.data.Get(key)
+		if !exists {
+			delete(c.volatileHashes, key)
+			continue
+		}
+		if fields := c.expireFields(key, e, now); len(fields) > 0 {
+			removed[key] = fields
+			expired += len(fields)
+		}
+	}
+	hook := c.fieldExpireHook
+	c.mu.Unlock()
+
+	if hook != nil {
+		for key, fields := range removed {
+			hook(key, fields)
+		}
+	}
+	return sampled, expired
+}
+
+// SetFieldExpireHook registers the function called with the expired fields
+// of every hash
+func (c *InMemoryCache) SetFieldExpireHook(hook func(key string, fields []string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fieldExpireHook = hook
+}
+
+// ExpiredFields returns the number of hash fields that expired
+func (c *InMemoryCache) ExpiredFields() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.expiredFields
+}
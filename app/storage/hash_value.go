@@ -0,0 +1,454 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/utility"
+)
+
+// Thresholds of the compact encoding of hashes, matching the defaults of
+// hash-max-listpack-entries and hash-max-listpack-value
+const (
+	HashMaxListpackEntries = 128
+	HashMaxListpackValue   = 64
+)
+
+// HashField is a field of a hash along with its value
+type HashField struct {
+	Field string
+	Value string
+}
+
+// HashValue represents a Redis hash. Small hashes are stored as a slice of
+// pairs, like the listpack encoding of Redis: scanning a few entries beats
+// hashing for them and they use less memory. Once a hash grows past the
+// thresholds it is converted to a Dict for good.
+//
+// Fields may expire on their own. Expired fields are hidden from reads right
+// away but only removed by ExpireFields, so that replicas keep them until
+// their master tells them to delete them, like keys.
+type HashValue struct {
+	*hashData
+	// keepExpired is set on the view of the hash the master of a replica
+	// writes through, which sees the expired fields, see IgnoringExpiry
+	keepExpired bool
+}
+
+// hashData is the content of a hash, shared by its views
+type hashData struct {
+	// pairs holds the fields in insertion order while the hash is small
+	pairs []HashField
+	// dict replaces pairs once the hash is converted
+	dict *Dict[string]
+	// expires holds the expiration time of the volatile fields
+	expires map[string]time.Time
+	mu      sync.RWMutex
+}
+
+func NewHashValue() *HashValue {
+	return &HashValue{hashData: &hashData{}}
+}
+
+// ignoringExpiry returns a view of the hash whose expired fields are still
+// there
+func (h *HashValue) ignoringExpiry() *HashValue {
+	return &HashValue{hashData: h.hashData, keepExpired: true}
+}
+
+func (h *HashValue) Type() string {
+	return "hash"
+}
+
+// Encoding returns the name Redis gives to the current encoding
+func (h *HashValue) Encoding() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.dict != nil {
+		return "hashtable"
+	}
+	return "listpack"
+}
+
+func (h *HashValue) MemoryUsage(samples int) int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	size := int64(elementOverhead + len(h.expires)*expireOverhead)
+	if h.dict == nil {
+		return size + sampledSize(len(h.pairs), samples, func(i int) int64 {
+			return elementOverhead + int64(len(h.pairs[i].Field)+len(h.pairs[i].Value))
+		})
+	}
+	// The dict can't be indexed, so the first samples fields in iteration
+	// order are measured instead
+	var total int64
+	measured := 0
+	h.dict.Range(func(field, value string) bool {
+		total += elementOverhead + int64(len(field)+len(value))
+		measured++
+		return samples <= 0 || measured < samples
+	})
+	if measured == 0 {
+		return size
+	}
+	return size + total*int64(h.dict.Len())/int64(measured)
+}
+
+func (h *HashValue) Copy() RedisValue {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	hash := NewHashValue()
+	if h.dict == nil {
+		hash.pairs = append([]HashField(nil), h.pairs...)
+	} else {
+		hash.dict = NewDict[string]()
+		h.dict.Range(func(field, value string) bool {
+			hash.dict.Set(field, value)
+			return true
+		})
+	}
+	if len(h.expires) > 0 {
+		hash.expires = make(map[string]time.Time, len(h.expires))
+		for field, expiresAt := range h.expires {
+			hash.expires[field] = expiresAt
+		}
+	}
+	return hash
+}
+
+// isExpired must be called with the lock held
+func (h *HashValue) isExpired(field string, now time.Time) bool {
+	if h.keepExpired {
+		return false
+	}
+	expiresAt, volatile := h.expires[field]
+	return volatile && !now.Before(expiresAt)
+}
+
+// Len returns the number of fields that didn't expire
+func (h *HashValue) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.len(time.Now())
+}
+
+func (h *HashValue) len(now time.Time) int {
+	size := len(h.pairs)
+	if h.dict != nil {
+		size = h.dict.Len()
+	}
+	for field := range h.expires {
+		if h.isExpired(field, now) {
+			size--
+		}
+	}
+	return size
+}
+
+// Get returns the value of field
+func (h *HashValue) Get(field string) (string, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.get(field, time.Now())
+}
+
+func (h *HashValue) get(field string, now time.Time) (string, bool) {
+	if h.isExpired(field, now) {
+		return "", false
+	}
+	if h.dict != nil {
+		return h.dict.Get(field)
+	}
+	for _, pair := range h.pairs {
+		if pair.Field == field {
+			return pair.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set stores value at field and removes its expiration, it reports whether
+// the field is new
+func (h *HashValue) Set(field, value string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.set(field, value, time.Now())
+}
+
+// SetNX stores value at field only when the field doesn't exist yet
+func (h *HashValue) SetNX(field, value string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	if _, exists := h.get(field, now); exists {
+		return false
+	}
+	return h.set(field, value, now)
+}
+
+func (h *HashValue) set(field, value string, now time.Time) bool {
+	// An expired field is replaced like a missing one
+	added := h.isExpired(field, now)
+	delete(h.expires, field)
+
+	if h.dict == nil && (len(field) > HashMaxListpackValue || len(value) > HashMaxListpackValue) {
+		h.convert()
+	}
+	if h.dict != nil {
+		return h.dict.Set(field, value) || added
+	}
+	for i, pair := range h.pairs {
+		if pair.Field == field {
+			h.pairs[i].Value = value
+			return added
+		}
+	}
+	h.pairs = append(h.pairs, HashField{Field: field, Value: value})
+	if len(h.pairs) > HashMaxListpackEntries {
+		h.convert()
+	}
+	return true
+}
+
+// convert switches to the Dict encoding, it must be called with the write
+// lock held
+func (h *HashValue) convert() {
+	h.dict = NewDict[string]()
+	for _, pair := range h.pairs {
+		h.dict.Set(pair.Field, pair.Value)
+	}
+	h.pairs = nil
+}
+
+// Delete removes fields and returns how many existed. Expired fields are
+// removed as well without being counted.
+func (h *HashValue) Delete(fields ...string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	deleted := 0
+	for _, field := range fields {
+		expired := h.isExpired(field, now)
+		if h.delete(field) && !expired {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+func (h *HashValue) delete(field string) bool {
+	delete(h.expires, field)
+	if h.dict != nil {
+		return h.dict.Delete(field)
+	}
+	for i, pair := range h.pairs {
+		if pair.Field == field {
+			h.pairs = append(h.pairs[:i], h.pairs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Entries returns every field that didn't expire along with its value
+func (h *HashValue) Entries() []HashField {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.entries(time.Now())
+}
+
+func (h *HashValue) entries(now time.Time) []HashField {
+	entries := make([]HashField, 0, h.len(now))
+	if h.dict == nil {
+		for _, pair := range h.pairs {
+			if !h.isExpired(pair.Field, now) {
+				entries = append(entries, pair)
+			}
+		}
+		return entries
+	}
+	h.dict.Range(func(field, value string) bool {
+		if !h.isExpired(field, now) {
+			entries = append(entries, HashField{Field: field, Value: value})
+		}
+		return true
+	})
+	return entries
+}
+
+// IncrBy adds delta to the integer stored at field, a missing field counts
+// as 0. The field keeps its expiration.
+func (h *HashValue) IncrBy(field string, delta int64) (int64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	current := int64(0)
+	if value, exists := h.get(field, now); exists {
+		var err error
+		current, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, errors.New("hash value is not an integer")
+		}
+	}
+	if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+		return 0, errors.New("increment or decrement would overflow")
+	}
+	current += delta
+	h.update(field, strconv.FormatInt(current, 10), now)
+	return current, nil
+}
+
+// IncrByFloat adds delta to the number stored at field, a missing field
+// counts as 0. It returns the new value as stored, the field keeps its
+// expiration.
+func (h *HashValue) IncrByFloat(field string, delta float64) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	current := 0.0
+	if value, exists := h.get(field, now); exists {
+		var err error
+		current, err = utility.ParseFloat(value)
+		if err != nil || math.IsInf(current, 0) {
+			return "", errors.New("hash value is not a float")
+		}
+	}
+	current += delta
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return "", errors.New("increment would produce NaN or Infinity")
+	}
+	value := utility.FormatFloat(current)
+	h.update(field, value, now)
+	return value, nil
+}
+
+// update changes the value of field, keeping the expiration of a field that
+// is still alive
+func (h *HashValue) update(field, value string, now time.Time) {
+	expiresAt, volatile := h.expires[field]
+	alive := !h.isExpired(field, now)
+	h.set(field, value, now)
+	if volatile && alive {
+		h.setExpire(field, expiresAt)
+	}
+}
+
+// Random returns count random fields. The fields are distinct unless
+// allowRepeats is set, in which case exactly count fields are returned.
+func (h *HashValue) Random(count int, allowRepeats bool) []HashField {
+	entries := h.Entries()
+	if len(entries) == 0 {
+		return entries
+	}
+	if allowRepeats {
+		picked := make([]HashField, count)
+		for i := range picked {
+			picked[i] = entries[rand.Intn(len(entries))]
+		}
+		return picked
+	}
+	if count >= len(entries) {
+		return entries
+	}
+	rand.Shuffle(len(entries), func(i, j int) {
+		entries[i], entries[j] = entries[j], entries[i]
+	})
+	return entries[:count]
+}
+
+// Scan returns the fields of the next buckets starting at cursor, along with
+// the cursor to continue from. Like Redis, hashes using the compact encoding
+// are returned whole in a single call.
+func (h *HashValue) Scan(cursor uint64, count int) (uint64, []HashField) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	now := time.Now()
+	if h.dict == nil {
+		return 0, h.entries(now)
+	}
+	fields := make([]HashField, 0, count)
+	collect := func(field, value string) {
+		if !h.isExpired(field, now) {
+			fields = append(fields, HashField{Field: field, Value: value})
+		}
+	}
+	for visited := 0; visited < count*10; visited++ {
+		cursor = h.dict.Scan(cursor, collect)
+		if cursor == 0 || len(fields) >= count {
+			break
+		}
+	}
+	return cursor, fields
+}
+
+// FieldExpiration returns when field expires, volatile is false when it has
+// no expiration and exists when the field doesn't exist
+func (h *HashValue) FieldExpiration(field string) (expiresAt time.Time, volatile bool, exists bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if _, exists := h.get(field, time.Now()); !exists {
+		return time.Time{}, false, false
+	}
+	expiresAt, volatile = h.expires[field]
+	return expiresAt, volatile, true
+}
+
+// SetFieldExpiration makes an existing field expire at expiresAt, it reports
+// false when the field doesn't exist
+func (h *HashValue) SetFieldExpiration(field string, expiresAt time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, exists := h.get(field, time.Now()); !exists {
+		return false
+	}
+	h.setExpire(field, expiresAt)
+	return true
+}
+
+// setExpire must be called with the write lock held
+func (h *HashValue) setExpire(field string, expiresAt time.Time) {
+	if h.expires == nil {
+		h.expires = make(map[string]time.Time)
+	}
+	h.expires[field] = expiresAt
+}
+
+// PersistField removes the expiration of field, it reports whether there was
+// one
+func (h *HashValue) PersistField(field string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.isExpired(field, time.Now()) {
+		return false
+	}
+	_, volatile := h.expires[field]
+	delete(h.expires, field)
+	return volatile
+}
+
+// HasVolatileFields reports whether some fields have an expiration
+func (h *HashValue) HasVolatileFields() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.expires) > 0
+}
+
+// ExpireFields removes the fields that expired and returns them
+func (h *HashValue) ExpireFields(now time.Time) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	expired := make([]string, 0)
+	for field := range h.expires {
+		if h.isExpired(field, now) {
+			expired = append(expired, field)
+		}
+	}
+	for _, field := range expired {
+		h.delete(field)
+	}
+	return expired
+}
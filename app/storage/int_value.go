@@ -1,16 +1,19 @@
 package storage
 
-import "time"
-
 type IntValue struct {
-	Val        int
-	Expiration time.Time
+	Val int
 }
 
 func (*IntValue) Type() string {
 	return "integer"
 }
 
-func (*IntValue) IsExpired(t time.Time) bool {
-	return false
+func (i *IntValue) Copy() RedisValue {
+	return &IntValue{Val: i.Val}
+}
+
+// MemoryUsage is zero, like Redis an integer is stored in place of the
+// pointer to its value
+func (*IntValue) MemoryUsage(samples int) int64 {
+	return 0
 }
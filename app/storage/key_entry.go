@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// LFU tunables, matching the defaults of Redis
+const (
+	// LFUInitVal is the counter of a new key, so fresh keys get a chance
+	// to accumulate accesses before being evicted
+	LFUInitVal = 5
+	// LFULogFactor slows the counter growth down, with the default factor
+	// the counter saturates at around a million accesses
+	LFULogFactor = 10
+	// LFUDecayTime is how many minutes it takes for the counter to be
+	// decremented once when the key isn't accessed
+	LFUDecayTime = 1
+	lfuMax       = 255
+)
+
+// keyEntry is a value along with the bookkeeping eviction needs. The access
+// fields are updated with atomics since reads only hold the read lock.
+type keyEntry struct {
+	value RedisValue
+	// size is the estimated memory footprint of the key and its value
+	size int64
+	// lru is the unix millisecond time of the last access
+	lru atomic.Int64
+	// lfu is the logarithmic access frequency counter
+	lfu atomic.Uint32
+	// lfuDecay is the unix minute the counter was last decremented
+	lfuDecay atomic.Int64
+}
+
+func newKeyEntry(key string, value RedisValue, now time.Time) *keyEntry {
+	e := &keyEntry{value: value, size: EstimateSize(key, value, bookkeepingSamples)}
+	e.lru.Store(now.UnixMilli())
+	e.lfu.Store(LFUInitVal)
+	e.lfuDecay.Store(now.Unix() / 60)
+	return e
+}
+
+// touch records an access to the key
+func (e *keyEntry) touch(now time.Time) {
+	e.lru.Store(now.UnixMilli())
+	counter := e.frequency(now)
+	e.lfuDecay.Store(now.Unix() / 60)
+	e.lfu.Store(lfuIncrement(counter))
+}
+
+// idle returns how long the key hasn't been accessed
+func (e *keyEntry) idle(now time.Time) time.Duration {
+	return time.Duration(now.UnixMilli()-e.lru.Load()) * time.Millisecond
+}
+
+// frequency returns the access counter after applying the decay of the
+// minutes elapsed since the last access
+func (e *keyEntry) frequency(now time.Time) uint32 {
+	counter := e.lfu.Load()
+	periods := (now.Unix()/60 - e.lfuDecay.Load()) / LFUDecayTime
+	if periods <= 0 {
+		return counter
+	}
+	if int64(counter) <= periods {
+		return 0
+	}
+	return counter - uint32(periods)
+}
+
+// lfuIncrement increments the counter with a probability that decreases as
+// the counter grows
+func lfuIncrement(counter uint32) uint32 {
+	if counter == lfuMax {
+		return counter
+	}
+	base := max(float64(counter)-LFUInitVal, 0)
+	if rand.Float64() < 1.0/(base*LFULogFactor+1) {
+		return counter + 1
+	}
+	return counter
+}
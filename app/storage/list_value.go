@@ -2,7 +2,6 @@ package storage
 
 import (
 	"sync"
-	"time"
 )
 
 type ListValue struct {
@@ -20,8 +19,18 @@ func (l *ListValue) Type() string {
 	return "list"
 }
 
-func (l *ListValue) IsExpired(t time.Time) bool {
-	return false
+func (l *ListValue) MemoryUsage(samples int) int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return elementOverhead + sampledSize(len(l.Items), samples, func(i int) int64 {
+		return elementOverhead + int64(len(l.Items[i].Value))
+	})
+}
+
+func (l *ListValue) Copy() RedisValue {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return &ListValue{Items: append([]ListItem(nil), l.Items...)}
 }
 
 func (l *ListValue) Size() int {
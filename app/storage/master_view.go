@@ -0,0 +1,100 @@
+package storage
+
+import "time"
+
+// masterView is the keyspace as the replication stream of a replica sees it:
+// keys that logically expired still exist, their removal is up to the DEL the
+// master sends. Otherwise clock skew could make a replica drop a PEXPIREAT or
+// a SET ... KEEPTTL its master applied. Redis does the same, expireIfNeeded
+// never expires keys for the master client.
+type masterView struct {
+	*InMemoryCache
+}
+
+// IgnoringExpiry returns a view of c for the commands a replica receives from
+// its master, whose lookups find expired keys
+func IgnoringExpiry(c Cache) Cache {
+	if cache, ok := c.(*InMemoryCache); ok {
+		return masterView{cache}
+	}
+	return c
+}
+
+// stored returns what the cache holds for a value that may come from a view,
+// a hash that is stored again must not keep seeing its expired fields
+func stored(value RedisValue) RedisValue {
+	if hash, ok := value.(*HashValue); ok && hash.keepExpired {
+		return &HashValue{hashData: hash.hashData}
+	}
+	return value
+}
+
+// Get retrieves a value from the cache whether it expired or not. The same
+// goes for the fields of a hash, their removal is up to the HDEL the master
+// sends.
+func (v masterView) Get(key string) (RedisValue, bool) {
+	v.mu.RLock()
+	e, exists := v.data.Get(key)
+	v.mu.RUnlock()
+	if !exists {
+		return nil, false
+	}
+	e.touch(time.Now())
+	if hash, ok := e.value.(*HashValue); ok {
+		return hash.ignoringExpiry(), true
+	}
+	return e.value, true
+}
+
+// Exists reports whether key exists, expired or not
+func (v masterView) Exists(key string) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	_, exists := v.data.Get(key)
+	return exists
+}
+
+// MemoryUsage estimates the memory used by key without counting as an
+// access, expired or not
+func (v masterView) MemoryUsage(key string, samples int) (int64, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	e, exists := v.data.Get(key)
+	if !exists {
+		return 0, false
+	}
+	return EstimateSize(key, e.value, samples), true
+}
+
+// Type returns the type of the value stored at key, expired or not
+func (v masterView) Type(key string) string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	e, exists := v.data.Get(key)
+	if !exists {
+		return "none"
+	}
+	return e.value.Type()
+}
+
+// SetExpiration sets the expiration time of a key, expired or not
+func (v masterView) SetExpiration(key string, expiresAt time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, exists := v.data.Get(key); !exists {
+		return false
+	}
+	v.setExpire(key, expiresAt)
+	return true
+}
+
+// Persist turns a volatile key into a persistent one, expired or not
+func (v masterView) Persist(key string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, volatile := v.expires[key]; !volatile {
+		return false
+	}
+	v.removeExpire(key)
+	return true
+}
@@ -0,0 +1,75 @@
+package storage
+
+// Approximate allocation overheads, in bytes, used to estimate how much
+// memory a key takes. They don't need to be exact, only to grow with the
+// data so maxmemory and MEMORY USAGE behave sensibly.
+const (
+	// keyOverhead covers the map slot, the key header and the access
+	// bookkeeping of every key
+	keyOverhead = 64
+	// expireOverhead is the cost of the expires entry of a volatile key
+	expireOverhead = 32
+	// stringOverhead is the header of a string allocation
+	stringOverhead = 16
+	// elementOverhead is the per element cost of aggregate values
+	elementOverhead = 24
+	// streamEntryOverhead covers an entry ID and its field map
+	streamEntryOverhead = 64
+)
+
+// bookkeepingSamples is how many elements the size kept for every key is
+// extrapolated from. The size is estimated again after each write, measuring
+// every element would make writes to large values linear in their length.
+const bookkeepingSamples = 5
+
+// MemoryStats breaks down the memory used by a database
+type MemoryStats struct {
+	Keys         int
+	VolatileKeys int
+	// Used is the total, data and bookkeeping
+	Used int64
+	// MainOverhead and ExpiresOverhead are the bookkeeping costs of the
+	// keys and of their expiration times
+	MainOverhead    int64
+	ExpiresOverhead int64
+}
+
+// Dataset returns the memory used by the data itself
+func (s MemoryStats) Dataset() int64 {
+	return s.Used - s.MainOverhead - s.ExpiresOverhead
+}
+
+// EstimateSize returns the approximate memory used by key and its value.
+// Aggregate values are extrapolated from their first samples elements, all
+// elements are measured when samples is 0.
+func EstimateSize(key string, value RedisValue, samples int) int64 {
+	return keyOverhead + stringOverhead + int64(len(key)) + value.MemoryUsage(samples)
+}
+
+// sampledSize sums size(i) over the first samples of n elements and
+// extrapolates the sum to all of them
+func sampledSize(n, samples int, size func(i int) int64) int64 {
+	measured := n
+	if samples > 0 && samples < n {
+		measured = samples
+	}
+	var total int64
+	for i := 0; i < measured; i++ {
+		total += size(i)
+	}
+	if measured == 0 {
+		return 0
+	}
+	return total * int64(n) / int64(measured)
+}
+
+// MemoryStats returns the memory breakdown of every database
+func (d *Databases) MemoryStats() []MemoryStats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	stats := make([]MemoryStats, 0, len(d.dbs))
+	for _, db := range d.dbs {
+		stats = append(stats, db.MemoryStats())
+	}
+	return stats
+}
@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// SetMaxIntsetEntries is the size past which a set of integers leaves the
+// intset encoding, matching the default of set-max-intset-entries
+const SetMaxIntsetEntries = 512
+
+// SetValue represents a Redis set. Sets whose members are all integers are
+// stored as a sorted slice of int64, like the intset encoding of Redis, which
+// is compact and answers membership with a binary search. Adding a member
+// that isn't an integer or growing past SetMaxIntsetEntries converts the set
+// to a Dict for good.
+type SetValue struct {
+	// ints holds the members in ascending order while the set is an intset
+	ints []int64
+	// dict replaces ints once the set is converted
+	dict *Dict[struct{}]
+	mu   sync.RWMutex
+}
+
+func NewSetValue() *SetValue {
+	return &SetValue{}
+}
+
+func (s *SetValue) Type() string {
+	return "set"
+}
+
+// Encoding returns the name Redis gives to the current encoding
+func (s *SetValue) Encoding() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.dict != nil {
+		return "hashtable"
+	}
+	return "intset"
+}
+
+func (s *SetValue) MemoryUsage(samples int) int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.dict == nil {
+		return int64(elementOverhead + 8*len(s.ints))
+	}
+	// The dict can't be indexed, so the first samples members in iteration
+	// order are measured instead
+	var total int64
+	measured := 0
+	s.dict.Range(func(member string, _ struct{}) bool {
+		total += elementOverhead + int64(len(member))
+		measured++
+		return samples <= 0 || measured < samples
+	})
+	if measured == 0 {
+		return elementOverhead
+	}
+	return elementOverhead + total*int64(s.dict.Len())/int64(measured)
+}
+
+func (s *SetValue) Copy() RedisValue {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	set := &SetValue{}
+	if s.dict == nil {
+		set.ints = slices.Clone(s.ints)
+		return set
+	}
+	set.dict = NewDict[struct{}]()
+	s.dict.Range(func(member string, _ struct{}) bool {
+		set.dict.Set(member, struct{}{})
+		return true
+	})
+	return set
+}
+
+// parseIntMember returns the integer member represents, ok is false unless
+// formatting the integer back gives member exactly, so that "01" or "+1"
+// stay strings
+func parseIntMember(member string) (int64, bool) {
+	value, err := strconv.ParseInt(member, 10, 64)
+	if err != nil || strconv.FormatInt(value, 10) != member {
+		return 0, false
+	}
+	return value, true
+}
+
+// Len returns the number of members
+func (s *SetValue) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.len()
+}
+
+func (s *SetValue) len() int {
+	if s.dict != nil {
+		return s.dict.Len()
+	}
+	return len(s.ints)
+}
+
+// Contains reports whether member belongs to the set
+func (s *SetValue) Contains(member string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.contains(member)
+}
+
+func (s *SetValue) contains(member string) bool {
+	if s.dict != nil {
+		_, exists := s.dict.Get(member)
+		return exists
+	}
+	value, ok := parseIntMember(member)
+	if !ok {
+		return false
+	}
+	_, found := slices.BinarySearch(s.ints, value)
+	return found
+}
+
+// Add adds members to the set and returns how many were new
+func (s *SetValue) Add(members ...string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := 0
+	for _, member := range members {
+		if s.add(member) {
+			added++
+		}
+	}
+	return added
+}
+
+func (s *SetValue) add(member string) bool {
+	if s.dict == nil {
+		value, ok := parseIntMember(member)
+		if !ok {
+			s.convert()
+			return s.dict.Set(member, struct{}{})
+		}
+		i, found := slices.BinarySearch(s.ints, value)
+		if found {
+			return false
+		}
+		s.ints = slices.Insert(s.ints, i, value)
+		if len(s.ints) > SetMaxIntsetEntries {
+			s.convert()
+		}
+		return true
+	}
+	return s.dict.Set(member, struct{}{})
+}
+
+// convert switches to the Dict encoding, it must be called with the write
+// lock held
+func (s *SetValue) convert() {
+	s.dict = NewDict[struct{}]()
+	for _, value := range s.ints {
+		s.dict.Set(strconv.FormatInt(value, 10), struct{}{})
+	}
+	s.ints = nil
+}
+
+// Remove removes members from the set and returns how many existed
+func (s *SetValue) Remove(members ...string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for _, member := range members {
+		if s.remove(member) {
+			removed++
+		}
+	}
+	return removed
+}
+
+func (s *SetValue) remove(member string) bool {
+	if s.dict != nil {
+		return s.dict.Delete(member)
+	}
+	value, ok := parseIntMember(member)
+	if !ok {
+		return false
+	}
+	i, found := slices.BinarySearch(s.ints, value)
+	if !found {
+		return false
+	}
+	s.ints = slices.Delete(s.ints, i, i+1)
+	return true
+}
+
+// Members returns every member, in ascending order for intsets
+func (s *SetValue) Members() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.members()
+}
+
+func (s *SetValue) members() []string {
+	members := make([]string, 0, s.len())
+	if s.dict == nil {
+		for _, value := range s.ints {
+			members = append(members, strconv.FormatInt(value, 10))
+		}
+		return members
+	}
+	s.dict.Range(func(member string, _ struct{}) bool {
+		members = append(members, member)
+		return true
+	})
+	return members
+}
+
+// random returns a random member of a non empty set
+func (s *SetValue) random() string {
+	if s.dict != nil {
+		member, _, _ := s.dict.Random()
+		return member
+	}
+	return strconv.FormatInt(s.ints[rand.Intn(len(s.ints))], 10)
+}
+
+// Random returns count random members. The members are distinct unless
+// allowRepeats is set, in which case exactly count members are returned.
+func (s *SetValue) Random(count int, allowRepeats bool) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	size := s.len()
+	if size == 0 {
+		return []string{}
+	}
+	if allowRepeats {
+		picked := make([]string, count)
+		for i := range picked {
+			picked[i] = s.random()
+		}
+		return picked
+	}
+	if count >= size {
+		return s.members()
+	}
+	return s.distinct(count, size)
+}
+
+// distinct picks count distinct random members, count must be smaller than
+// size. Like Redis, asking for most of the set shuffles a copy of it while
+// asking for a few members picks them one at a time until enough are unique.
+func (s *SetValue) distinct(count, size int) []string {
+	if count*3 > size {
+		members := s.members()
+		rand.Shuffle(len(members), func(i, j int) {
+			members[i], members[j] = members[j], members[i]
+		})
+		return members[:count]
+	}
+	picked := make([]string, 0, count)
+	seen := make(map[string]struct{}, count)
+	for len(picked) < count {
+		member := s.random()
+		if _, dup := seen[member]; dup {
+			continue
+		}
+		seen[member] = struct{}{}
+		picked = append(picked, member)
+	}
+	return picked
+}
+
+// Pop removes count distinct random members and returns them
+func (s *SetValue) Pop(count int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	size := s.len()
+	if count >= size {
+		members := s.members()
+		s.ints, s.dict = nil, nil
+		return members
+	}
+	popped := s.distinct(count, size)
+	for _, member := range popped {
+		s.remove(member)
+	}
+	return popped
+}
+
+// Scan returns the members of the next buckets starting at cursor, along
+// with the cursor to continue from. Like Redis, intsets are returned whole in
+// a single call.
+func (s *SetValue) Scan(cursor uint64, count int) (uint64, []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.dict == nil {
+		return 0, s.members()
+	}
+	members := make([]string, 0, count)
+	collect := func(member string, _ struct{}) {
+		members = append(members, member)
+	}
+	for visited := 0; visited < count*10; visited++ {
+		cursor = s.dict.Scan(cursor, collect)
+		if cursor == 0 || len(members) >= count {
+			break
+		}
+	}
+	return cursor, members
+}
@@ -0,0 +1,311 @@
+package storage
+
+import "math/rand"
+
+// Skiplist parameters, the same as Redis: with p = 1/4 a node has 1.33
+// levels on average and 32 levels are plenty for 2^64 elements
+const (
+	skiplistMaxLevel = 32
+	skiplistP        = 0.25
+)
+
+// skiplistNode is an element of a skiplist. Every level records how many
+// nodes its forward pointer skips, which is what makes rank lookups
+// logarithmic.
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	levels   []skiplistLevel
+}
+
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int
+}
+
+// skiplist keeps the members of a sorted set ordered by score, then by
+// member. It is a port of the zskiplist of Redis.
+type skiplist struct {
+	header *skiplistNode
+	tail   *skiplistNode
+	length int
+	level  int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: &skiplistNode{levels: make([]skiplistLevel, skiplistMaxLevel)},
+		level:  1,
+	}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+	return level
+}
+
+// before reports whether n sorts before the element (score, member)
+func (n *skiplistNode) before(score float64, member string) bool {
+	return n.score < score || (n.score == score && n.member < member)
+}
+
+// insert adds an element, the member must not be in the skiplist already
+func (sl *skiplist) insert(score float64, member string) *skiplistNode {
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		if i < sl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.levels[i].forward != nil && x.levels[i].forward.before(score, member) {
+			rank[i] += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > sl.level {
+		for i := sl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = sl.header
+			update[i].levels[i].span = sl.length
+		}
+		sl.level = level
+	}
+
+	x = &skiplistNode{member: member, score: score, levels: make([]skiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.levels[i].forward = update[i].levels[i].forward
+		update[i].levels[i].forward = x
+		x.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+	// The levels above the new node now skip one more element
+	for i := level; i < sl.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	if update[0] != sl.header {
+		x.backward = update[0]
+	}
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x
+	} else {
+		sl.tail = x
+	}
+	sl.length++
+	return x
+}
+
+// findUpdate returns, for every level, the last node before (score, member)
+func (sl *skiplist) findUpdate(score float64, member string) [skiplistMaxLevel]*skiplistNode {
+	var update [skiplistMaxLevel]*skiplistNode
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && x.levels[i].forward.before(score, member) {
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+	return update
+}
+
+// deleteNode unlinks x given the nodes preceding it on every level
+func (sl *skiplist) deleteNode(x *skiplistNode, update [skiplistMaxLevel]*skiplistNode) {
+	for i := 0; i < sl.level; i++ {
+		if update[i].levels[i].forward == x {
+			update[i].levels[i].span += x.levels[i].span - 1
+			update[i].levels[i].forward = x.levels[i].forward
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x.backward
+	} else {
+		sl.tail = x.backward
+	}
+	for sl.level > 1 && sl.header.levels[sl.level-1].forward == nil {
+		sl.level--
+	}
+	sl.length--
+}
+
+// delete removes the element (score, member), it reports whether it existed
+func (sl *skiplist) delete(score float64, member string) bool {
+	update := sl.findUpdate(score, member)
+	x := update[0].levels[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+	sl.deleteNode(x, update)
+	return true
+}
+
+// updateScore moves member from score to newScore. The node is updated in
+// place when it keeps its position, which is the common case of small
+// increments.
+func (sl *skiplist) updateScore(score float64, member string, newScore float64) {
+	update := sl.findUpdate(score, member)
+	x := update[0].levels[0].forward
+	if (x.backward == nil || x.backward.before(newScore, member)) &&
+		(x.levels[0].forward == nil || !x.levels[0].forward.before(newScore, member)) {
+		x.score = newScore
+		return
+	}
+	sl.deleteNode(x, update)
+	sl.insert(newScore, member)
+}
+
+// rank returns the 1-based rank of the element (score, member), 0 when it
+// isn't in the skiplist
+func (sl *skiplist) rank(score float64, member string) int {
+	rank := 0
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil &&
+			(x.levels[i].forward.before(score, member) || (x.levels[i].forward.score == score && x.levels[i].forward.member == member)) {
+			rank += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		if x != sl.header && x.score == score && x.member == member {
+			return rank
+		}
+	}
+	return 0
+}
+
+// byRank returns the node at the 1-based rank
+func (sl *skiplist) byRank(rank int) *skiplistNode {
+	traversed := 0
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && traversed+x.levels[i].span <= rank {
+			traversed += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		if traversed == rank {
+			return x
+		}
+	}
+	return nil
+}
+
+// ZRange is a range of a sorted set, either a ScoreRange or a LexRange
+type ZRange interface {
+	// gteMin and lteMax report whether n is past the start of the range and
+	// before its end
+	gteMin(n *skiplistNode) bool
+	lteMax(n *skiplistNode) bool
+	isEmpty() bool
+}
+
+// firstInRange returns the first node in r, nil when there is none
+func (sl *skiplist) firstInRange(r ZRange) *skiplistNode {
+	if r.isEmpty() {
+		return nil
+	}
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !r.gteMin(x.levels[i].forward) {
+			x = x.levels[i].forward
+		}
+	}
+	x = x.levels[0].forward
+	if x == nil || !r.lteMax(x) {
+		return nil
+	}
+	return x
+}
+
+// lastInRange returns the last node in r, nil when there is none
+func (sl *skiplist) lastInRange(r ZRange) *skiplistNode {
+	if r.isEmpty() {
+		return nil
+	}
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && r.lteMax(x.levels[i].forward) {
+			x = x.levels[i].forward
+		}
+	}
+	if x == sl.header || !r.gteMin(x) {
+		return nil
+	}
+	return x
+}
+
+// ScoreRange is a range of scores, each end may be excluded
+type ScoreRange struct {
+	Min, Max     float64
+	MinExclusive bool
+	MaxExclusive bool
+}
+
+func (r ScoreRange) gteMin(n *skiplistNode) bool {
+	if r.MinExclusive {
+		return n.score > r.Min
+	}
+	return n.score >= r.Min
+}
+
+func (r ScoreRange) lteMax(n *skiplistNode) bool {
+	if r.MaxExclusive {
+		return n.score < r.Max
+	}
+	return n.score <= r.Max
+}
+
+func (r ScoreRange) isEmpty() bool {
+	return r.Min > r.Max || (r.Min == r.Max && (r.MinExclusive || r.MaxExclusive))
+}
+
+// LexBound is an end of a LexRange. Inf is -1 for "-", the bound below every
+// member, and 1 for "+", the bound above every member.
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	Inf       int
+}
+
+// LexRange is a range of members, meaningful when they all share a score
+type LexRange struct {
+	Min, Max LexBound
+}
+
+func (r LexRange) gteMin(n *skiplistNode) bool {
+	switch {
+	case r.Min.Inf != 0:
+		return r.Min.Inf < 0
+	case r.Min.Exclusive:
+		return n.member > r.Min.Value
+	}
+	return n.member >= r.Min.Value
+}
+
+func (r LexRange) lteMax(n *skiplistNode) bool {
+	switch {
+	case r.Max.Inf != 0:
+		return r.Max.Inf > 0
+	case r.Max.Exclusive:
+		return n.member < r.Max.Value
+	}
+	return n.member <= r.Max.Value
+}
+
+func (r LexRange) isEmpty() bool {
+	if r.Min.Inf > 0 || r.Max.Inf < 0 {
+		return true
+	}
+	if r.Min.Inf < 0 || r.Max.Inf > 0 {
+		return false
+	}
+	return r.Min.Value > r.Max.Value ||
+		(r.Min.Value == r.Max.Value && (r.Min.Exclusive || r.Max.Exclusive))
+}
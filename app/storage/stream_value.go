@@ -104,9 +104,26 @@ func (s *StreamValue) Type() string {
 	return "stream"
 }
 
-func (s *StreamValue) IsExpired(currentTime time.Time) bool {
-	// Streams don't expire by default
-	return false
+func (s *StreamValue) Copy() RedisValue {
+	entries := make([]StreamEntry, len(s.Entries))
+	for i, entry := range s.Entries {
+		fields := make(map[string]string, len(entry.Fields))
+		for field, val := range entry.Fields {
+			fields[field] = val
+		}
+		entries[i] = StreamEntry{ID: entry.ID, Fields: fields}
+	}
+	return &StreamValue{Entries: entries}
+}
+
+func (s *StreamValue) MemoryUsage(samples int) int64 {
+	return elementOverhead + sampledSize(len(s.Entries), samples, func(i int) int64 {
+		size := int64(streamEntryOverhead)
+		for field, val := range s.Entries[i].Fields {
+			size += 2*stringOverhead + int64(len(field)+len(val))
+		}
+		return size
+	})
 }
 
 // GetEntries returns the stream entries
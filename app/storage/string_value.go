@@ -1,19 +1,20 @@
 package storage
 
-import "time"
-
-// StringValue represents a Redis string value with optional expiration
+// StringValue represents a Redis string value
 type StringValue struct {
-	Val        string
-	Expiration time.Time
+	Val string
 }
 
 func (s *StringValue) Type() string {
 	return "string"
 }
 
-func (s *StringValue) IsExpired(currentTime time.Time) bool {
-	return !s.Expiration.IsZero() && currentTime.Compare(s.Expiration) >= 0
+func (s *StringValue) MemoryUsage(samples int) int64 {
+	return stringOverhead + int64(len(s.Val))
+}
+
+func (s *StringValue) Copy() RedisValue {
+	return &StringValue{Val: s.Val}
 }
 
 // GetValue returns the string value
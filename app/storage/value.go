@@ -1,9 +1,12 @@
 package storage
 
-import "time"
-
-// RedisValue represents any value that can be stored in Redis
+// RedisValue represents any value that can be stored in Redis. Expiration is
+// tracked by the keyspace, not by the values themselves.
 type RedisValue interface {
 	Type() string
-	IsExpired(currentTime time.Time) bool
+	// MemoryUsage estimates the bytes used by the value, extrapolating from
+	// the first samples elements of aggregates, or all of them when 0
+	MemoryUsage(samples int) int64
+	// Copy returns a deep copy of the value, which shares nothing with it
+	Copy() RedisValue
 }
@@ -0,0 +1,381 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+var ErrScoreNaN = errors.New("resulting score is not a number (NaN)")
+
+// Options of ZSetValue.Add, the flags of ZADD
+const (
+	ZAddNX = 1 << iota
+	ZAddXX
+	ZAddGT
+	ZAddLT
+	ZAddIncr
+)
+
+// ZAddOutcome tells what ZSetValue.Add did
+type ZAddOutcome int
+
+const (
+	// ZAddSkipped means a NX, XX, GT or LT condition wasn't met
+	ZAddSkipped ZAddOutcome = iota
+	ZAddUnchanged
+	ZAddAdded
+	ZAddUpdated
+)
+
+// ZSetEntry is a member of a sorted set along with its score
+type ZSetEntry struct {
+	Member string
+	Score  float64
+}
+
+// ZSetValue represents a Redis sorted set. Like Redis, the members are kept
+// both in a skiplist ordered by score, for ranges and ranks in O(log n), and
+// in a Dict mapping them to their score, for lookups in O(1) and ZSCAN.
+type ZSetValue struct {
+	dict *Dict[float64]
+	zsl  *skiplist
+	mu   sync.RWMutex
+}
+
+func NewZSetValue() *ZSetValue {
+	return &ZSetValue{dict: NewDict[float64](), zsl: newSkiplist()}
+}
+
+func (z *ZSetValue) Type() string {
+	return "zset"
+}
+
+// Encoding returns the name Redis gives to the current encoding
+func (z *ZSetValue) Encoding() string {
+	return "skiplist"
+}
+
+func (z *ZSetValue) MemoryUsage(samples int) int64 {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	// Every member has a dict entry and a skiplist node, of about 1.33
+	// levels, that both refer to the same string
+	var total int64
+	measured := 0
+	for x := z.zsl.header.levels[0].forward; x != nil; x = x.levels[0].forward {
+		if samples > 0 && measured >= samples {
+			break
+		}
+		total += 2*elementOverhead + int64(len(x.member)) + 8
+		measured++
+	}
+	if measured == 0 {
+		return elementOverhead
+	}
+	return elementOverhead + total*int64(z.zsl.length)/int64(measured)
+}
+
+func (z *ZSetValue) Copy() RedisValue {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	zset := NewZSetValue()
+	for x := z.zsl.header.levels[0].forward; x != nil; x = x.levels[0].forward {
+		zset.dict.Set(x.member, x.score)
+		zset.zsl.insert(x.score, x.member)
+	}
+	return zset
+}
+
+// Len returns the number of members
+func (z *ZSetValue) Len() int {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	return z.zsl.length
+}
+
+// Score returns the score of member
+func (z *ZSetValue) Score(member string) (float64, bool) {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	return z.dict.Get(member)
+}
+
+// Add sets the score of member following the ZADD flags and returns the
+// resulting score. With ZAddIncr, score is added to the current one.
+func (z *ZSetValue) Add(member string, score float64, flags int) (float64, ZAddOutcome, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	current, exists := z.dict.Get(member)
+	if !exists {
+		if flags&ZAddXX != 0 {
+			return 0, ZAddSkipped, nil
+		}
+		z.dict.Set(member, score)
+		z.zsl.insert(score, member)
+		return score, ZAddAdded, nil
+	}
+
+	if flags&ZAddNX != 0 {
+		return current, ZAddSkipped, nil
+	}
+	if flags&ZAddIncr != 0 {
+		score += current
+		if math.IsNaN(score) {
+			return 0, ZAddSkipped, ErrScoreNaN
+		}
+	}
+	if (flags&ZAddGT != 0 && score <= current) || (flags&ZAddLT != 0 && score >= current) {
+		return current, ZAddSkipped, nil
+	}
+	if score == current {
+		return current, ZAddUnchanged, nil
+	}
+	z.zsl.updateScore(current, member, score)
+	z.dict.Set(member, score)
+	return score, ZAddUpdated, nil
+}
+
+// Remove removes members and returns how many existed
+func (z *ZSetValue) Remove(members ...string) int {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	removed := 0
+	for _, member := range members {
+		if score, exists := z.dict.Get(member); exists {
+			z.remove(score, member)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (z *ZSetValue) remove(score float64, member string) {
+	z.dict.Delete(member)
+	z.zsl.delete(score, member)
+}
+
+// Rank returns the 0-based rank of member along with its score, counting
+// from the highest score when reverse is set
+func (z *ZSetValue) Rank(member string, reverse bool) (int, float64, bool) {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	score, exists := z.dict.Get(member)
+	if !exists {
+		return 0, 0, false
+	}
+	rank := z.zsl.rank(score, member)
+	if reverse {
+		return z.zsl.length - rank, score, true
+	}
+	return rank - 1, score, true
+}
+
+// normalizeRanks turns start and stop, which count from the end when they
+// are negative, into valid indexes. ok is false when the range is empty.
+func normalizeRanks(start, stop, length int) (int, int, bool) {
+	if start < 0 {
+		start = max(length+start, 0)
+	}
+	if stop < 0 {
+		stop = length + stop
+	}
+	stop = min(stop, length-1)
+	return start, stop, start <= stop
+}
+
+// RangeByRank returns the members from rank start to rank stop included,
+// negative ranks count from the end. With reverse, ranks count from the
+// highest score.
+func (z *ZSetValue) RangeByRank(start, stop int, reverse bool) []ZSetEntry {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	return z.rangeByRank(start, stop, reverse)
+}
+
+func (z *ZSetValue) rangeByRank(start, stop int, reverse bool) []ZSetEntry {
+	start, stop, ok := normalizeRanks(start, stop, z.zsl.length)
+	if !ok {
+		return []ZSetEntry{}
+	}
+	entries := make([]ZSetEntry, 0, stop-start+1)
+	if !reverse {
+		for x := z.zsl.byRank(start + 1); x != nil && len(entries) < cap(entries); x = x.levels[0].forward {
+			entries = append(entries, ZSetEntry{Member: x.member, Score: x.score})
+		}
+		return entries
+	}
+	for x := z.zsl.byRank(z.zsl.length - start); x != nil && len(entries) < cap(entries); x = x.backward {
+		entries = append(entries, ZSetEntry{Member: x.member, Score: x.score})
+	}
+	return entries
+}
+
+// RangeBy returns the members in r, from the lowest score unless reverse
+// is set. The first offset members are skipped and at most limit members are
+// returned, a negative limit means no limit.
+func (z *ZSetValue) RangeBy(r ZRange, reverse bool, offset, limit int) []ZSetEntry {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	return z.rangeBy(r, reverse, offset, limit)
+}
+
+func (z *ZSetValue) rangeBy(r ZRange, reverse bool, offset, limit int) []ZSetEntry {
+	entries := make([]ZSetEntry, 0)
+	var x *skiplistNode
+	if reverse {
+		x = z.zsl.lastInRange(r)
+	} else {
+		x = z.zsl.firstInRange(r)
+	}
+	if x == nil || offset < 0 {
+		return entries
+	}
+	// Skipping by rank keeps large offsets logarithmic
+	if offset > 0 {
+		rank := z.zsl.rank(x.score, x.member)
+		if reverse {
+			rank -= offset
+		} else {
+			rank += offset
+		}
+		if rank < 1 || rank > z.zsl.length {
+			return entries
+		}
+		x = z.zsl.byRank(rank)
+	}
+	for ; x != nil && limit != 0; limit-- {
+		if reverse {
+			if !r.gteMin(x) {
+				break
+			}
+		} else if !r.lteMax(x) {
+			break
+		}
+		entries = append(entries, ZSetEntry{Member: x.member, Score: x.score})
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.levels[0].forward
+		}
+	}
+	return entries
+}
+
+// Count returns the number of members in r
+func (z *ZSetValue) Count(r ZRange) int {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	first := z.zsl.firstInRange(r)
+	if first == nil {
+		return 0
+	}
+	last := z.zsl.lastInRange(r)
+	return z.zsl.rank(last.score, last.member) - z.zsl.rank(first.score, first.member) + 1
+}
+
+// RemoveRangeByRank removes the members from rank start to rank stop
+// included and returns how many were removed
+func (z *ZSetValue) RemoveRangeByRank(start, stop int) int {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	entries := z.rangeByRank(start, stop, false)
+	for _, entry := range entries {
+		z.remove(entry.Score, entry.Member)
+	}
+	return len(entries)
+}
+
+// RemoveRangeBy removes the members in r and returns how many were removed
+func (z *ZSetValue) RemoveRangeBy(r ZRange) int {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	entries := z.rangeBy(r, false, 0, -1)
+	for _, entry := range entries {
+		z.remove(entry.Score, entry.Member)
+	}
+	return len(entries)
+}
+
+// Pop removes the count members with the lowest scores, or the highest ones
+// when highest is set, and returns them in pop order
+func (z *ZSetValue) Pop(count int, highest bool) []ZSetEntry {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	entries := z.rangeByRank(0, count-1, highest)
+	for _, entry := range entries {
+		z.remove(entry.Score, entry.Member)
+	}
+	return entries
+}
+
+// Entries returns every member in ascending order
+func (z *ZSetValue) Entries() []ZSetEntry {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	return z.rangeByRank(0, -1, false)
+}
+
+// Random returns count random members. The members are distinct unless
+// allowRepeats is set, in which case exactly count members are returned.
+func (z *ZSetValue) Random(count int, allowRepeats bool) []ZSetEntry {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	size := z.zsl.length
+	if size == 0 {
+		return []ZSetEntry{}
+	}
+	random := func() ZSetEntry {
+		member, score, _ := z.dict.Random()
+		return ZSetEntry{Member: member, Score: score}
+	}
+	if allowRepeats {
+		picked := make([]ZSetEntry, count)
+		for i := range picked {
+			picked[i] = random()
+		}
+		return picked
+	}
+	if count >= size {
+		return z.rangeByRank(0, -1, false)
+	}
+	// Like SetValue.Random, a large share of the set is shuffled and a small
+	// one is picked a member at a time
+	if count*3 > size {
+		entries := z.rangeByRank(0, -1, false)
+		rand.Shuffle(len(entries), func(i, j int) {
+			entries[i], entries[j] = entries[j], entries[i]
+		})
+		return entries[:count]
+	}
+	picked := make([]ZSetEntry, 0, count)
+	seen := make(map[string]struct{}, count)
+	for len(picked) < count {
+		entry := random()
+		if _, dup := seen[entry.Member]; dup {
+			continue
+		}
+		seen[entry.Member] = struct{}{}
+		picked = append(picked, entry)
+	}
+	return picked
+}
+
+// Scan returns the members of the next buckets of the member dict starting
+// at cursor, along with the cursor to continue from
+func (z *ZSetValue) Scan(cursor uint64, count int) (uint64, []ZSetEntry) {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	entries := make([]ZSetEntry, 0, count)
+	collect := func(member string, score float64) {
+		entries = append(entries, ZSetEntry{Member: member, Score: score})
+	}
+	for visited := 0; visited < count*10; visited++ {
+		cursor = z.dict.Scan(cursor, collect)
+		if cursor == 0 || len(entries) >= count {
+			break
+		}
+	}
+	return cursor, entries
+}
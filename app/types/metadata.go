@@ -5,6 +5,8 @@ import (
 	"log"
 	"net"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,23 +30,36 @@ type WaitRequest struct {
 	StartTime     time.Time
 }
 
+// ReplicationBatch is a group of commands that replicas apply back to back,
+// against database DB
+type ReplicationBatch struct {
+	DB       int
+	Commands [][]string
+}
+
 type ServerMetadata struct {
 	// Replication info
-	Role                       string        `json:"role"`
-	ConnectedSlaves            int           `json:"connected_slaves"`
-	MasterReplID               string        `json:"master_replid"`
-	MasterReplOffset           int64         `json:"master_repl_offset"`
-	SecondReplOffset           int64         `json:"second_repl_offset"`
-	ReplBacklogActive          int           `json:"repl_backlog_active"`
-	ReplBacklogSize            int64         `json:"repl_backlog_size"`
-	ReplBacklogFirstByteOffset int64         `json:"repl_backlog_first_byte_offset"`
-	ReplBacklogHistlen         int64         `json:"repl_backlog_histlen"`
-	ReplActiveConnection       []net.Conn    `json:"-"`
-	ReplChannel                chan []string `json:"-"`
-	ShutdownChannel            chan struct{} `json:"-"`
-	CommandProcessed           int64         `json:"-"`
-	Dir                        string        `json:"-"`
-	DbFileName                 string        `json:"-"`
+	Role                       string                `json:"role"`
+	ConnectedSlaves            int                   `json:"connected_slaves"`
+	MasterReplID               string                `json:"master_replid"`
+	MasterReplOffset           int64                 `json:"master_repl_offset"`
+	SecondReplOffset           int64                 `json:"second_repl_offset"`
+	ReplBacklogActive          int                   `json:"repl_backlog_active"`
+	ReplBacklogSize            int64                 `json:"repl_backlog_size"`
+	ReplBacklogFirstByteOffset int64                 `json:"repl_backlog_first_byte_offset"`
+	ReplBacklogHistlen         int64                 `json:"repl_backlog_histlen"`
+	ReplActiveConnection       []net.Conn            `json:"-"`
+	ReplChannel                chan ReplicationBatch `json:"-"`
+	ShutdownChannel            chan struct{}         `json:"-"`
+	CommandProcessed           int64                 `json:"-"`
+	Dir                        string                `json:"-"`
+	DbFileName                 string                `json:"-"`
+	Databases                  int                   `json:"-"`
+	MaxMemory                  int64                 `json:"-"`
+	MaxMemoryPolicy            string                `json:"-"`
+
+	// replicationDB is the database the replication stream last selected
+	replicationDB int
 
 	// WAIT command support
 	AckResponseChannel chan AckResponse        `json:"-"`
@@ -110,9 +125,10 @@ func (m *ServerMetadata) UpdateMasterOffset(bytes int64) {
 func (m *ServerMetadata) ReplicateCommandToReplicas() {
 	for {
 		select {
-		case Cmd := <-m.ReplChannel:
-			// send the command to replicas
-			m.Replicate(Cmd)
+		case batch := <-m.ReplChannel:
+			// send the commands to replicas, a batch (e.g. MULTI ... EXEC)
+			// is never interleaved with commands from other clients
+			m.replicateBatch(batch)
 		case <-m.ShutdownChannel:
 			log.Println("Replication work is shutting down")
 			return
@@ -120,10 +136,37 @@ func (m *ServerMetadata) ReplicateCommandToReplicas() {
 	}
 }
 
+// replicateBatch sends a batch to replicas, selecting its database first
+// whenever the stream is positioned on another one
+func (m *ServerMetadata) replicateBatch(batch ReplicationBatch) {
+	m.mutex.RLock()
+	selected := m.replicationDB
+	m.mutex.RUnlock()
+
+	if batch.DB != selected {
+		m.Replicate([]string{"SELECT", strconv.Itoa(batch.DB)})
+		selected = batch.DB
+	}
+	for _, Cmd := range batch.Commands {
+		m.Replicate(Cmd)
+		// A transaction may switch databases midway
+		if strings.ToUpper(Cmd[0]) == "SELECT" && len(Cmd) > 1 {
+			selected, _ = strconv.Atoi(Cmd[1])
+		}
+	}
+
+	m.mutex.Lock()
+	m.replicationDB = selected
+	m.mutex.Unlock()
+}
+
 func (m *ServerMetadata) AddReplicasConnection(conn net.Conn) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 	m.ReplActiveConnection = append(m.ReplActiveConnection, conn)
+	// A new replica starts out on database 0, make the stream select
+	// explicitly again
+	m.replicationDB = -1
 
 	// Generate connection ID for this replica
 	connID := fmt.Sprintf("replica-%p", conn)
@@ -198,7 +241,8 @@ func NewServerMetadata(role string) *ServerMetadata {
 	// ctx, _ := context.WithTimeout(context.Background(), 2*time.Second)
 	metadata := ServerMetadata{
 		Role:                 role,
-		ReplChannel:          make(chan []string, 1_000),
+		ReplChannel:          make(chan ReplicationBatch, 1_000),
+		replicationDB:        -1,
 		ReplActiveConnection: make([]net.Conn, 0),
 		AckResponseChannel:   make(chan AckResponse, 100),
 		WaitRequests:         make(map[string]*WaitRequest),
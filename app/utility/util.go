@@ -1,7 +1,11 @@
 package utility
 
 import (
+	"fmt"
+	"math"
 	"math/rand"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -22,3 +26,66 @@ func ConvertStringArrayToAny(slice []string) []any {
 	}
 	return result
 }
+
+// memoryUnits are the suffixes accepted by ParseMemory, like in redis.conf
+// "k" is 1000 bytes while "kb" is 1024
+var memoryUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+	{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000},
+	{"b", 1},
+}
+
+// ParseMemory parses a memory amount such as "100mb" into bytes
+func ParseMemory(value string) (int64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range memoryUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSuffix(value, unit.suffix)
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	amount, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || amount < 0 || amount > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("invalid memory amount %q", value)
+	}
+	return amount * multiplier, nil
+}
+
+// BytesToHuman formats a number of bytes the way INFO does, e.g. "1.50M"
+func BytesToHuman(n int64) string {
+	units := []string{"B", "K", "M", "G", "T"}
+	value := float64(n)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%dB", n)
+	}
+	return fmt.Sprintf("%.2f%s", value, units[unit])
+}
+
+// ParseFloat parses a number the way Redis does: infinities are accepted,
+// NaN and surrounding spaces aren't
+func ParseFloat(value string) (float64, error) {
+	if value != strings.TrimSpace(value) {
+		return 0, fmt.Errorf("invalid float %q", value)
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, fmt.Errorf("invalid float %q", value)
+	}
+	return f, nil
+}
+
+// FormatFloat formats a number stored in a string value, without exponent
+// and with the shortest representation that parses back to it
+func FormatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}